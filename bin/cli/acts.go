@@ -8,9 +8,12 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"strconv"
-	"time"
+	"strings"
+	_ "time/tzdata"
 
 	"bytes"
 	"github.com/Fepelus/ActivityStream/boundaries"
@@ -20,44 +23,96 @@ import (
 
 func main() {
 	cmdToFunc := map[string]func([]string){
-		"new":    newItem,
-		"add":    newItem,
-		"done":   doneItem,
-		"del":    doneItem,
-		"delete": doneItem,
-		"delay":  delayItem,
-		"grep":   grepItems,
-		"get":    getActivity,
-		"help":   help,
-	}
-	if len(os.Args) < 2 {
+		"new":        newItem,
+		"add":        newItem,
+		"done":       doneItem,
+		"cancel":     cancelItem,
+		"del":        cancelItem,
+		"delete":     cancelItem,
+		"delay":      delayItem,
+		"reschedule": rescheduleItem,
+		"edit":       editItem,
+		"grep":       grepItems,
+		"history":    historyItem,
+		"compact":    compactLog,
+		"migrate":    migrateIds,
+		"undo":       undoOperations,
+		"get":        getActivity,
+		"agenda":     agendaItems,
+		"stats":      statsItems,
+		"export":     exportItems,
+		"import":     importItems,
+		"help":       help,
+	}
+	if err := entities.ConfigureZone(os.Getenv("ACTS_TZ")); err != nil {
+		fail(err)
+	}
+	args, err := takeFormat(os.Args[1:])
+	if err != nil {
+		fail(err)
+	}
+	if len(args) < 1 {
 		help([]string{})
-	} else if function, ok := cmdToFunc[os.Args[1]]; ok {
-		function(os.Args[2:])
+	} else if function, ok := cmdToFunc[args[0]]; ok {
+		function(args[1:])
 	} else {
 		help([]string{})
 	}
 }
 
-func getLogfile() boundaries.Logfile {
-	if os.Getenv("ACTS_LOGFILE") != "" {
-		return boundaries.Logfile{os.Getenv("ACTS_LOGFILE")}
+var store usecases.Store
+
+// clock is the time the commands take it to be. 'get --at' moves it.
+var clock entities.Clock = entities.SystemClock{}
+
+// getStore opens the storage named by ACTS_STORE, or else the logfile named
+// by ACTS_LOGFILE, or else logfile.txt
+func getStore() usecases.Store {
+	if store != nil {
+		return store
+	}
+	location := os.Getenv("ACTS_STORE")
+	if location == "" {
+		location = os.Getenv("ACTS_LOGFILE")
+	}
+	if location == "" {
+		location = "logfile.txt"
+	}
+	opened, err := boundaries.Open(location)
+	if err != nil {
+		fail(err)
 	}
-	return boundaries.Logfile{"logfile.txt"}
+	opened.Clock = clock
+	store = opened
+	return store
 }
 
 func newItem(args []string) {
-	if doesNotPassTheCheck(args) {
+	if isRepeatCommand(args) {
+		handleRepeatCommand(args)
 		return
 	}
-	wholeInput := concatenate(args)
-
-	activity, err := entities.ParseOneActivity(wholeInput)
+	when, rest, err := entities.ParseWhen(args, clock.Now())
 	if err != nil {
-		fmt.Println("You probably meant to say 'new now'")
+		fail(err)
+	}
+	if len(rest) == 0 {
+		fmt.Println("Arguments to newItem were only:", args)
+		help(args)
 		return
 	}
-	fmt.Println(usecases.AddItem(activity, getLogfile()))
+	activity := entities.OneActivity{Timestamp: when}.WithText(concatenate(rest))
+	hash, err := usecases.AddItem(activity, getStore())
+	if err != nil {
+		fail(err)
+	}
+	printResult(hash)
+}
+
+// fail reports an error from a use case and stops with a non-zero status
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, plain(os.Stderr, err.Error()))
+	os.Exit(1)
 }
 
 func concatenate(args []string) string {
@@ -71,57 +126,84 @@ func concatenate(args []string) string {
 	return buffer.String()
 }
 
-func doesNotPassTheCheck(args []string) bool {
-	if isNowCommand(args) {
-		handleNowCommand(args)
-		return true
-	}
-	if isTodayCommand(args) {
-		handleTodayCommand(args)
-		return true
+func isRepeatCommand(args []string) bool {
+	return len(args) > 0 && args[0] == "repeat"
+}
+func handleRepeatCommand(args []string) {
+	rule, rest, err := entities.ParseRepeatWords(args[1:])
+	if err != nil {
+		fail(err)
 	}
-	if len(args) < 3 {
+	if len(rest) == 0 {
 		fmt.Println("Arguments to newItem were only:", args)
 		help(args)
-		return true
+		return
 	}
-	return false
-}
-
-func isNowCommand(args []string) bool {
-	return len(args) > 1 && args[0] == "now"
-}
-func handleNowCommand(args []string) {
-	now := time.Now()
-	newargs := todayWithTime(args, now.Format("15:04"))
-	newItem(newargs)
+	first := rule.First(clock.Now().In(entities.Zone))
+	newargs := []string{first.Format("2006-01-02"), first.Format("15:04"), "@rtask:" + rule.Tag()}
+	newItem(append(newargs, rest...))
 }
 
-func isTodayCommand(args []string) bool {
-	_, err := time.Parse("15:04", args[0])
-	return err == nil
-}
-func handleTodayCommand(args []string) {
-	newargs := todayWithTime(args, args[0])
-	newItem(newargs)
+// takeFilter reads the filter words, such as '+work' and '-@home', from
+// anywhere in the arguments, and returns the arguments without them
+func takeFilter(args []string) (entities.Filter, []string) {
+	words := []string{}
+	rest := []string{}
+	for _, arg := range args {
+		if entities.IsFilterWord(arg) {
+			words = append(words, arg)
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	filter, _ := entities.ParseFilter(words)
+	return filter, rest
 }
 
-func todayWithTime(args []string, timestamp string) []string {
-	now := time.Now()
-	newargs := make([]string, len(args)+1)
-	newargs[0] = now.Format("2006-01-02")
-	newargs[1] = timestamp
-	for i := 2; i < len(args)+1; i++ {
-		newargs[i] = args[i-1]
+// takeSort reads a '--sort ORDER' or '--sort=ORDER' from anywhere in the
+// arguments, and returns the order ("time" if not given) and the arguments
+// without it
+func takeSort(args []string) (string, []string) {
+	order := "time"
+	rest := []string{}
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--sort" && i+1 < len(args):
+			order = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--sort="):
+			order = strings.TrimPrefix(args[i], "--sort=")
+		default:
+			rest = append(rest, args[i])
+		}
 	}
-	return newargs
+	return order, rest
 }
 
 func getActivity(args []string) {
-	items := usecases.GetActivity(getLogfile())
-	for _, el := range items {
-		fmt.Println(el)
+	//get [--sort time|priority] [+project] [-@context] [--at YYYY-MM-DDTHH:MM [zone]]
+	order, args := takeSort(args)
+	filter, args := takeFilter(args)
+	if len(args) > 1 && args[0] == "--at" {
+		at, err := entities.ParseTimestamp(strings.Join(append([]string{strings.Replace(args[1], "T", " ", 1)}, args[2:]...), " "))
+		if err != nil {
+			fail(err)
+		}
+		clock = entities.FixedClock{Time: at}
+	}
+	if format != "text" {
+		due, err := usecases.DueActivities(filter, order, getStore(), clock)
+		if err != nil {
+			fail(err)
+		}
+		printActivities(due)
+		return
+	}
+	items, err := usecases.GetActivity(filter, order, getStore(), clock)
+	if err != nil {
+		fail(err)
 	}
+	printLines(items)
 }
 
 func doneItem(args []string) {
@@ -130,9 +212,22 @@ func doneItem(args []string) {
 		help(args)
 		return
 	}
-	err := usecases.MarkActivityAsDone(args[0], getLogfile())
+	hash, err := usecases.MarkActivityAsDone(args[0], getStore(), clock)
+	printResult(hash)
 	if err != nil {
-		fmt.Print(err)
+		fail(err)
+	}
+}
+
+func cancelItem(args []string) {
+	//cancel ID
+	if len(args) < 1 {
+		fmt.Println("Arguments to cancelItem were only:", args)
+		help(args)
+		return
+	}
+	if err := usecases.CancelActivity(args[0], getStore()); err != nil {
+		fail(err)
 	}
 }
 
@@ -142,12 +237,37 @@ func grepItems(args []string) {
 		help(args)
 		return
 	}
-	grepped := usecases.GrepItems("("+args[0], getLogfile())
-	if grepped != nil {
-		for _, el := range grepped {
-			fmt.Println(el)
+	grepped, err := usecases.GrepItems("("+args[0], getStore())
+	if err != nil {
+		fail(err)
+	}
+	if format != "text" {
+		printLogLines(grepped)
+		return
+	}
+	printLines(grepped)
+}
+
+func historyItem(args []string) {
+	//history ID
+	if len(args) != 1 {
+		fmt.Println("Arguments to historyItem were:", args)
+		help(args)
+		return
+	}
+	if format != "text" {
+		events, err := usecases.ActivityHistory(args[0], getStore())
+		if err != nil {
+			fail(err)
 		}
+		printEvents(events)
+		return
 	}
+	lines, err := usecases.GetHistory(args[0], getStore())
+	if err != nil {
+		fail(err)
+	}
+	printLines(lines)
 }
 
 func delayItem(args []string) {
@@ -158,11 +278,11 @@ func delayItem(args []string) {
 		return
 	}
 	if len(args) == 1 {
-		if hash, err := usecases.DelayActivity(args[0], 1, "day", getLogfile()); err != nil {
-			fmt.Print(err)
+		if hash, err := usecases.DelayActivity(args[0], 1, "day", getStore()); err != nil {
+			fail(err)
 		} else {
-            fmt.Println(hash)
-        }
+			printResult(hash)
+		}
 		return
 	}
 	if len(args) == 2 {
@@ -172,31 +292,338 @@ func delayItem(args []string) {
 	}
 	count, err := strconv.Atoi(args[1])
 	if err != nil {
-		fmt.Print(err)
+		fail(err)
 	}
-	if hash, err := usecases.DelayActivity(args[0], count, args[2], getLogfile()); err != nil {
-		fmt.Print(err)
+	if hash, err := usecases.DelayActivity(args[0], count, args[2], getStore()); err != nil {
+		fail(err)
 	} else {
-        fmt.Println(hash)
-    }
+		printResult(hash)
+	}
+}
+
+func rescheduleItem(args []string) {
+	//reschedule ID when
+	if len(args) < 2 {
+		fmt.Println("Arguments to rescheduleItem were only:", args)
+		help(args)
+		return
+	}
+	stamp, err := entities.ParseMoment(concatenate(args[1:]), clock.Now())
+	if err != nil {
+		fail(err)
+	}
+	if hash, err := usecases.RescheduleActivity(args[0], stamp, getStore()); err != nil {
+		fail(err)
+	} else {
+		printResult(hash)
+	}
+}
+
+func editItem(args []string) {
+	//edit ID [text]
+	if len(args) < 1 {
+		fmt.Println("Arguments to editItem were only:", args)
+		help(args)
+		return
+	}
+	edit := editInEditor
+	if len(args) > 1 {
+		edit = usecases.NewText(concatenate(args[1:]))
+	}
+	if hash, err := usecases.EditActivity(args[0], edit, getStore()); err != nil {
+		fail(err)
+	} else {
+		printResult(hash)
+	}
+}
+
+// editInEditor opens $EDITOR, or vi, on the text and returns what it was
+// changed to, with any line breaks turned into spaces
+func editInEditor(text string) (string, error) {
+	f, err := os.CreateTemp("", "acts-edit-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(text + "\n"); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	command := exec.Command("sh", "-c", editor+` "$1"`, "sh", f.Name())
+	command.Stdin, command.Stdout, command.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := command.Run(); err != nil {
+		return "", fmt.Errorf("The editor '%s' failed: %s", editor, err)
+	}
+	edited, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	lines := []string{}
+	for _, line := range strings.Split(string(edited), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, " "), nil
+}
+
+func compactLog(args []string) {
+	//compact [archive]
+	if len(args) > 1 || (len(args) == 1 && args[0] != "archive") {
+		fmt.Println("Arguments to compactLog were:", args)
+		help(args)
+		return
+	}
+	removed, archived, err := usecases.CompactLog(len(args) == 1, getStore())
+	if err != nil {
+		fail(err)
+	}
+	fmt.Printf("Removed %d lines of history\n", removed)
+	if archived != "" {
+		fmt.Printf("They are kept in %s\n", archived)
+	}
+}
+
+func migrateIds(args []string) {
+	//migrate
+	if len(args) > 0 {
+		fmt.Println("Arguments to migrateIds were:", args)
+		help(args)
+		return
+	}
+	migrated, err := usecases.MigrateIds(getStore())
+	if err != nil {
+		fail(err)
+	}
+	fmt.Printf("Gave new IDs to %d activities\n", migrated)
 }
 
-func parseActivity(datebit, timebit, body string) (entities.OneActivity, error) {
-	loc, _ := time.LoadLocation("Australia/Melbourne")
-	stamp, err := time.ParseInLocation("2006-01-02 15:04", fmt.Sprintf("%s %s", datebit, timebit), loc)
+// agendaUnits are the one-letter units that may follow a count, as in 'agenda 3d'
+var agendaUnits = map[string]string{"h": "hours", "d": "days", "w": "weeks", "m": "months"}
+
+func agendaItems(args []string) {
+	//agenda [count unit | countU] [+project] [-@context]
+	filter, args := takeFilter(args)
+	count, unit, ok := takePeriod(args)
+	if !ok {
+		fmt.Println("Arguments to agendaItems were:", args)
+		help(args)
+		return
+	}
+	if format != "text" {
+		upcoming, err := usecases.UpcomingActivities(count, unit, filter, getStore(), clock)
+		if err != nil {
+			fail(err)
+		}
+		printActivities(upcoming)
+		return
+	}
+	items, err := usecases.GetAgenda(count, unit, filter, getStore(), clock)
 	if err != nil {
-		return entities.OneActivity{}, err
+		fail(err)
+	}
+	printLines(items)
+}
+
+// takePeriod reads a period given as a count and a unit, or as '3d', or
+// not at all, which is a week
+func takePeriod(args []string) (int, string, bool) {
+	switch len(args) {
+	case 0:
+		return 1, "week", true
+	case 1:
+		n, err := strconv.Atoi(strings.TrimRight(args[0], "hdwm"))
+		u, ok := agendaUnits[strings.TrimLeft(args[0], "0123456789")]
+		return n, u, err == nil && ok
+	case 2:
+		n, err := strconv.Atoi(args[0])
+		return n, args[1], err == nil
+	}
+	return 0, "", false
+}
+
+func statsItems(args []string) {
+	//stats [count unit | countU]
+	count, unit, ok := takePeriod(args)
+	if !ok {
+		fmt.Println("Arguments to statsItems were:", args)
+		help(args)
+		return
+	}
+	if format != "text" {
+		stats, err := usecases.CompletionStats(count, unit, getStore(), clock)
+		if err != nil {
+			fail(err)
+		}
+		printStats(stats)
+		return
+	}
+	lines, err := usecases.GetStats(count, unit, getStore(), clock)
+	if err != nil {
+		fail(err)
+	}
+	printLines(lines)
+}
+
+func undoOperations(args []string) {
+	//undo [n]
+	n := 1
+	if len(args) > 0 {
+		count, err := strconv.Atoi(args[0])
+		if err != nil || count < 1 {
+			fmt.Println("Arguments to undoOperations were:", args)
+			help(args)
+			return
+		}
+		n = count
+	}
+	undone, err := usecases.UndoOperations(n, getStore())
+	printLines(undone)
+	if err != nil {
+		fail(err)
+	}
+}
+
+// exchangeFormats are the other programs' files that export and import
+// understand, named as they are given on the command line
+var exchangeFormats = map[string]struct {
+	write func(io.Writer, entities.Activities) error
+	read  func(io.Reader) (entities.Activities, error)
+}{
+	"--ical": {
+		func(w io.Writer, activities entities.Activities) error {
+			return boundaries.WriteICalendar(w, activities, clock.Now())
+		},
+		boundaries.ReadICalendar,
+	},
+	"--todotxt": {
+		boundaries.WriteTodoTxt,
+		func(r io.Reader) (entities.Activities, error) { return boundaries.ReadTodoTxt(r, clock.Now()) },
+	},
+	"--taskwarrior": {
+		boundaries.WriteTaskwarrior,
+		func(r io.Reader) (entities.Activities, error) { return boundaries.ReadTaskwarrior(r, clock.Now()) },
+	},
+}
+
+func exportItems(args []string) {
+	//export --ical|--todotxt|--taskwarrior [file]
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Println("Arguments to exportItems were:", args)
+		help(args)
+		return
+	}
+	exchange, ok := exchangeFormats[args[0]]
+	if !ok {
+		fmt.Println("Arguments to exportItems were:", args)
+		help(args)
+		return
+	}
+	live, err := usecases.LiveActivities(getStore())
+	if err != nil {
+		fail(err)
+	}
+	if len(args) == 1 || args[1] == "-" {
+		if err := exchange.write(os.Stdout, live); err != nil {
+			fail(err)
+		}
+		return
+	}
+	output, err := os.Create(args[1])
+	if err != nil {
+		fail(err)
+	}
+	if err := exchange.write(output, live); err != nil {
+		fail(err)
+	}
+	if err := output.Close(); err != nil {
+		fail(err)
+	}
+}
+
+func importItems(args []string) {
+	//import [--ical|--todotxt|--taskwarrior] file
+	name := "--ical"
+	switch {
+	case len(args) == 2:
+		name, args = args[0], args[1:]
+	case len(args) == 1 && strings.HasSuffix(args[0], ".txt"):
+		name = "--todotxt"
+	case len(args) == 1 && strings.HasSuffix(args[0], ".json"):
+		name = "--taskwarrior"
+	}
+	exchange, ok := exchangeFormats[name]
+	if !ok || len(args) != 1 {
+		fmt.Println("Arguments to importItems were:", args)
+		help(args)
+		return
+	}
+	input := os.Stdin
+	if args[0] != "-" {
+		opened, err := os.Open(args[0])
+		if err != nil {
+			fail(err)
+		}
+		defer opened.Close()
+		input = opened
+	}
+	activities, err := exchange.read(input)
+	if err != nil {
+		fail(err)
+	}
+	added, skipped, err := usecases.ImportActivities(activities, getStore())
+	fmt.Printf("Added %d activities; %d were already there\n", len(added), skipped)
+	if err != nil {
+		fail(err)
 	}
-	return entities.OneActivity{"", stamp, "", body}, nil
 }
 
 func help(args []string) {
-	fmt.Printf(`%s cmd [args]
+	fmt.Printf(`%s [--format FORMAT] cmd [args]
     help
-    get
-    new [date] [time] [body]
+    get [--sort time|priority] [--at YYYY-MM-DDTHH:MM [zone]] [filter]
+    agenda [count] [unit] (or '3d', '12h', '2w'; a week if not given) [filter]
+    stats [count] [unit] (days, weeks or months back; a week if not given)
+    new [when] [body]
+    new repeat every [count] [unit] [from time] [body]
+    new repeat after [count] [unit] [body]
     done [ID]
+    cancel [ID] (or del, delete)
     grep [ID]
+    history [ID]
     delay [ID] [count] [unit] ('minutes' 'hours' 'days' 'weeks' 'months')
+    reschedule [ID] [when]
+    edit [ID] [text] (in $EDITOR if no text is given)
+    compact [archive]
+    migrate
+    undo [count]
+    export --ical|--todotxt|--taskwarrior [file]
+    import [--ical|--todotxt|--taskwarrior] [file] (by its extension if not given)
+
+A time to new or reschedule is written as 'now', '2014-01-01 12:00', '17:30',
+'tomorrow 9am', 'fri 17:30', 'next monday', 'end of month', 'in 3 hours' or
+'+2d'. A day with no time starts at midnight.
+
+Times are in the zone named by $ACTS_TZ, or this computer's zone. Write a
+zone such as 'tz:Europe/Berlin' or 'tz:+02:00' after any time to use that
+instead.
+
+Write '!1', '!2' or '!3' after the time of a new item to give it a priority,
+'!1' being the most urgent. 'get --sort priority' lists those first.
+
+A filter is any number of '+project' and '@context' words that an item's
+text must have, and '-+project' and '-@context' words that it must not.
+
+FORMAT is text, json, jsonl, csv, tsv or a template such as
+'{{.ShortId}} {{.Timestamp}} {{.Body}}', and applies to get, agenda, grep,
+history and the ID printed by new, done, delay, reschedule and edit. stats
+may be printed as text, json or jsonl.
 `, os.Args[0])
 }
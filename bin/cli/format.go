@@ -0,0 +1,343 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/boundaries"
+	"github.com/Fepelus/ActivityStream/entities"
+	"github.com/Fepelus/ActivityStream/usecases"
+)
+
+// A record is an activity as the formats other than text print it. The csv
+// and tsv formats leave out the projects and contexts, which are in the
+// body anyway. Predecessor is the ID of the activity it was delayed or
+// rescheduled from, if it was. Logged and Command are only set for the lines
+// that grep finds and the events of history, whose Command is what happened.
+type record struct {
+	Id          string   `json:"id"`
+	ShortId     string   `json:"short_id"`
+	Timestamp   string   `json:"timestamp"`
+	CommandTag  string   `json:"command_tag"`
+	Priority    int      `json:"priority"`
+	Body        string   `json:"body"`
+	Projects    []string `json:"projects"`
+	Contexts    []string `json:"contexts"`
+	Predecessor string   `json:"predecessor,omitempty"`
+	Logged      string   `json:"logged,omitempty"`
+	Command     string   `json:"command,omitempty"`
+}
+
+func toRecord(activity entities.OneActivity) record {
+	return record{
+		Id:          activity.Id,
+		ShortId:     activity.DisplayId(),
+		Timestamp:   activity.Timestamp.Format(time.RFC3339),
+		CommandTag:  activity.CommandTag,
+		Priority:    activity.Priority,
+		Body:        activity.Body,
+		Projects:    activity.Projects(),
+		Contexts:    activity.Contexts(),
+		Predecessor: activity.Predecessor,
+	}
+}
+
+var formats = []string{"text", "json", "jsonl", "csv", "tsv"}
+
+// format is how results are printed, chosen with '--format': one of the
+// formats above, or "template" to execute outputTemplate for each record
+var format = "text"
+var outputTemplate *template.Template
+
+// takeFormat sets the format from a '--format NAME' or '--format=NAME'
+// given before the command, and returns the command and its arguments.
+// After the command '--format' is left alone, as it may be part of the text
+// of a new or edited item.
+func takeFormat(args []string) ([]string, error) {
+	for len(args) > 0 {
+		name := ""
+		switch {
+		case args[0] == "--format" && len(args) > 1:
+			name = args[1]
+			args = args[2:]
+		case strings.HasPrefix(args[0], "--format="):
+			name = strings.TrimPrefix(args[0], "--format=")
+			args = args[1:]
+		default:
+			return args, nil
+		}
+		if err := setFormat(name); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+func setFormat(name string) error {
+	for _, known := range formats {
+		if name == known {
+			format = name
+			return nil
+		}
+	}
+	if !strings.Contains(name, "{{") {
+		return fmt.Errorf("Format '%s' not found. Legal formats are '%s' or a template such as '{{.ShortId}} {{.Body}}'",
+			name, strings.Join(formats, "','"))
+	}
+	parsed, err := template.New("format").Parse(name)
+	if err != nil {
+		return err
+	}
+	format = "template"
+	outputTemplate = parsed
+	return nil
+}
+
+var escapePattern = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// colour says whether to keep the escape codes that make IDs bold: only
+// when writing to a terminal, and not when $NO_COLOR is set
+func colour(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func plain(f *os.File, line string) string {
+	if colour(f) {
+		return line
+	}
+	return escapePattern.ReplaceAllString(line, "")
+}
+
+// printLines prints the lines of the text format
+func printLines(lines []string) {
+	for _, line := range lines {
+		fmt.Println(plain(os.Stdout, line))
+	}
+}
+
+func printActivities(activities entities.Activities) {
+	records := []record{}
+	for _, activity := range activities {
+		records = append(records, toRecord(activity))
+	}
+	printRecords(records, false)
+}
+
+// printResult prints the ID a command returns: on its own in the text
+// format, otherwise as the record of the activity it identifies
+func printResult(hash string) {
+	if format == "text" {
+		if hash != "" {
+			fmt.Println(hash)
+		}
+		return
+	}
+	activities := entities.Activities{}
+	if hash != "" {
+		found, err := getStore().FindActivity(hash)
+		if err != nil {
+			fail(err)
+		}
+		for _, activity := range found {
+			activity.ShortId = hash
+			activities = append(activities, activity)
+		}
+	}
+	printActivities(activities)
+}
+
+// printLogLines prints the lines that grep found, parsed back into records
+func printLogLines(lines []string) {
+	records := []record{}
+	for _, line := range lines {
+		logline, err := boundaries.ParseLogLine(line)
+		if err != nil {
+			fail(err)
+		}
+		r := toRecord(logline.Activity)
+		r.Logged = logline.Now.Format(time.RFC3339)
+		r.Command = logline.Command
+		records = append(records, r)
+	}
+	printRecords(records, true)
+}
+
+// printEvents prints what happened to an activity, with the time of each
+// event as when it was logged
+func printEvents(events []usecases.Event) {
+	records := []record{}
+	for _, event := range events {
+		r := toRecord(event.Activity)
+		r.Logged = event.At.Format(time.RFC3339)
+		r.Command = event.What
+		records = append(records, r)
+	}
+	printRecords(records, true)
+}
+
+// printRecords prints in any format but text. withLog adds the fields
+// that grep fills in to the csv and tsv formats.
+func printRecords(records []record, withLog bool) {
+	if err := writeRecords(os.Stdout, records, withLog); err != nil {
+		fail(err)
+	}
+}
+
+func writeRecords(w io.Writer, records []record, withLog bool) error {
+	var err error
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(records)
+	case "jsonl":
+		encoder := json.NewEncoder(w)
+		for _, r := range records {
+			if err = encoder.Encode(r); err != nil {
+				break
+			}
+		}
+	case "csv", "tsv":
+		err = writeTable(w, records, withLog)
+	case "template":
+		for _, r := range records {
+			if err = outputTemplate.Execute(w, r); err != nil {
+				break
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	return err
+}
+
+// writeTable writes the csv and tsv formats, which start with a line naming
+// the columns. Fields in the tsv format are never quoted, so tabs and line
+// breaks in them are written as spaces.
+func writeTable(w io.Writer, records []record, withLog bool) error {
+	rows := [][]string{{"id", "short_id", "timestamp", "command_tag", "priority", "body"}}
+	if withLog {
+		rows[0] = append(rows[0], "logged", "command")
+	}
+	for _, r := range records {
+		priority := ""
+		if r.Priority != 0 {
+			priority = strconv.Itoa(r.Priority)
+		}
+		row := []string{r.Id, r.ShortId, r.Timestamp, r.CommandTag, priority, r.Body}
+		if withLog {
+			row = append(row, r.Logged, r.Command)
+		}
+		rows = append(rows, row)
+	}
+
+	if format == "csv" {
+		writer := csv.NewWriter(w)
+		writer.WriteAll(rows)
+		return writer.Error()
+	}
+	spaces := strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+	for _, row := range rows {
+		for i := range row {
+			row[i] = spaces.Replace(row[i])
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statsRecord is the Stats as the json and jsonl formats print them, with
+// lateness and slips in minutes
+type statsRecord struct {
+	From            string             `json:"from"`
+	Until           string             `json:"until"`
+	Done            int                `json:"done"`
+	Cancelled       int                `json:"cancelled"`
+	Late            int                `json:"late"`
+	AverageLateness int                `json:"average_lateness_minutes"`
+	Days            []tallyRecord      `json:"days"`
+	Weeks           []tallyRecord      `json:"weeks"`
+	MostDelayed     []delayChainRecord `json:"most_delayed"`
+	Streaks         []streakRecord     `json:"streaks"`
+}
+
+type tallyRecord struct {
+	Start     string `json:"start"`
+	Done      int    `json:"done"`
+	Cancelled int    `json:"cancelled"`
+}
+
+type delayChainRecord struct {
+	Activity record `json:"activity"`
+	Delays   int    `json:"delays"`
+	Slipped  int    `json:"slipped_minutes"`
+	Outcome  string `json:"outcome,omitempty"`
+}
+
+type streakRecord struct {
+	Activity record `json:"activity"`
+	Length   int    `json:"length"`
+}
+
+func toTallyRecords(tallies []usecases.Tally) []tallyRecord {
+	output := []tallyRecord{}
+	for _, tally := range tallies {
+		output = append(output, tallyRecord{tally.Start.Format(time.RFC3339), tally.Done, tally.Cancelled})
+	}
+	return output
+}
+
+// printStats prints the stats as one JSON object, which is also the one
+// line of the jsonl format
+func printStats(stats usecases.Stats) {
+	output := statsRecord{
+		From:            stats.From.Format(time.RFC3339),
+		Until:           stats.Until.Format(time.RFC3339),
+		Done:            stats.Done,
+		Cancelled:       stats.Cancelled,
+		Late:            stats.Late,
+		AverageLateness: int(stats.AverageLateness / time.Minute),
+		Days:            toTallyRecords(stats.Days),
+		Weeks:           toTallyRecords(stats.Weeks),
+		MostDelayed:     []delayChainRecord{},
+		Streaks:         []streakRecord{},
+	}
+	for _, chain := range stats.MostDelayed {
+		output.MostDelayed = append(output.MostDelayed,
+			delayChainRecord{toRecord(chain.Activity), chain.Delays, int(chain.Slipped / time.Minute), chain.Outcome})
+	}
+	for _, streak := range stats.Streaks {
+		output.Streaks = append(output.Streaks, streakRecord{toRecord(streak.Activity), streak.Length})
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	switch format {
+	case "json":
+		encoder.SetIndent("", "  ")
+	case "jsonl":
+	default:
+		fail(fmt.Errorf("stats may be printed as text, json or jsonl, not %s", format))
+	}
+	if err := encoder.Encode(output); err != nil {
+		fail(err)
+	}
+}
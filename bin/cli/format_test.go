@@ -0,0 +1,136 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTakeFormat(t *testing.T) {
+	tests := []struct {
+		args   string
+		format string
+		want   string
+	}{
+		{"get", "text", "get"},
+		{"--format json get", "json", "get"},
+		{"--format=csv get +home", "csv", "get +home"},
+		{"--format csv --format tsv grep abc", "tsv", "grep abc"},
+		{"new tomorrow explain --format json to Sam", "text", "new tomorrow explain --format json to Sam"},
+		{"--format jsonl edit abc use --format=csv instead", "jsonl", "edit abc use --format=csv instead"},
+		{"--format", "text", "--format"},
+	}
+	for _, test := range tests {
+		format = "text"
+		args, err := takeFormat(strings.Fields(test.args))
+		if err != nil {
+			t.Errorf("%q: %s", test.args, err)
+			continue
+		}
+		if got := strings.Join(args, " "); got != test.want || format != test.format {
+			t.Errorf("%q: got %q as %s, want %q as %s", test.args, got, format, test.want, test.format)
+		}
+	}
+	format = "text"
+}
+
+func TestBadFormats(t *testing.T) {
+	for _, name := range []string{"xml", "{{.Body", "{{.Body | nosuchfunc}}"} {
+		format = "text"
+		if _, err := takeFormat([]string{"--format", name, "get"}); err == nil {
+			t.Errorf("%q: got no error", name)
+		}
+		if format != "text" {
+			t.Errorf("%q: format became %s", name, format)
+		}
+	}
+
+	if err := setFormat("{{.NoSuchField}}"); err != nil {
+		t.Fatal(err)
+	}
+	var output bytes.Buffer
+	if err := writeRecords(&output, []record{{Id: "abc"}}, false); err == nil {
+		t.Errorf("executing a template with no such field got no error")
+	}
+	format = "text"
+}
+
+func TestWriteRecords(t *testing.T) {
+	records := []record{
+		{Id: "abc123", ShortId: "a", Timestamp: "2014-01-01T12:00:00Z", Body: "Wash the car +home",
+			Projects: []string{"home"}, Contexts: []string{}},
+		{Id: "def456", ShortId: "d", Timestamp: "2014-01-02T09:00:00Z", CommandTag: "rtask:every:1:week", Priority: 2,
+			Body: "Buy milk, eggs and \"good\" bread\tquickly", Projects: []string{}, Contexts: []string{},
+			Logged: "2014-01-01T08:00:00Z", Command: "ADD"},
+	}
+	tests := []struct {
+		format  string
+		withLog bool
+		want    string
+	}{
+		{"json", false, `[
+  {
+    "id": "abc123",
+    "short_id": "a",
+    "timestamp": "2014-01-01T12:00:00Z",
+    "command_tag": "",
+    "priority": 0,
+    "body": "Wash the car +home",
+    "projects": [
+      "home"
+    ],
+    "contexts": []
+  },
+  {
+    "id": "def456",
+    "short_id": "d",
+    "timestamp": "2014-01-02T09:00:00Z",
+    "command_tag": "rtask:every:1:week",
+    "priority": 2,
+    "body": "Buy milk, eggs and \"good\" bread\tquickly",
+    "projects": [],
+    "contexts": [],
+    "logged": "2014-01-01T08:00:00Z",
+    "command": "ADD"
+  }
+]
+`},
+		{"jsonl", false, `{"id":"abc123","short_id":"a","timestamp":"2014-01-01T12:00:00Z","command_tag":"","priority":0,"body":"Wash the car +home","projects":["home"],"contexts":[]}
+{"id":"def456","short_id":"d","timestamp":"2014-01-02T09:00:00Z","command_tag":"rtask:every:1:week","priority":2,"body":"Buy milk, eggs and \"good\" bread\tquickly","projects":[],"contexts":[],"logged":"2014-01-01T08:00:00Z","command":"ADD"}
+`},
+		{"csv", false, `id,short_id,timestamp,command_tag,priority,body
+abc123,a,2014-01-01T12:00:00Z,,,Wash the car +home
+def456,d,2014-01-02T09:00:00Z,rtask:every:1:week,2,"Buy milk, eggs and ""good"" bread	quickly"
+`},
+		{"csv", true, `id,short_id,timestamp,command_tag,priority,body,logged,command
+abc123,a,2014-01-01T12:00:00Z,,,Wash the car +home,,
+def456,d,2014-01-02T09:00:00Z,rtask:every:1:week,2,"Buy milk, eggs and ""good"" bread	quickly",2014-01-01T08:00:00Z,ADD
+`},
+		{"tsv", false, "id\tshort_id\ttimestamp\tcommand_tag\tpriority\tbody\n" +
+			"abc123\ta\t2014-01-01T12:00:00Z\t\t\tWash the car +home\n" +
+			"def456\td\t2014-01-02T09:00:00Z\trtask:every:1:week\t2\tBuy milk, eggs and \"good\" bread quickly\n"},
+		{"{{.ShortId}} {{.Priority}} {{.Body}}", false, "a 0 Wash the car +home\n" +
+			"d 2 Buy milk, eggs and \"good\" bread\tquickly\n"},
+	}
+	for _, test := range tests {
+		if err := setFormat(test.format); err != nil {
+			t.Errorf("%s: %s", test.format, err)
+			continue
+		}
+		var output bytes.Buffer
+		if err := writeRecords(&output, records, test.withLog); err != nil {
+			t.Errorf("%s: %s", test.format, err)
+			continue
+		}
+		if got := output.String(); got != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.format, got, test.want)
+		}
+	}
+	format = "text"
+}
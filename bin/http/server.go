@@ -7,189 +7,427 @@ See LICENSE.txt for terms of usage.
 package main
 
 import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	_ "time/tzdata"
+	"unicode"
 
-	"bytes"
 	"github.com/Fepelus/ActivityStream/boundaries"
 	"github.com/Fepelus/ActivityStream/entities"
 	"github.com/Fepelus/ActivityStream/usecases"
 )
 
+// The browser front-end is served at / and uses the REST interface:
+//
+//	GET  /activities                the activities that are due, or due by ?at=2014-01-01T12:00,
+//	                                with ?filter=%2Bwork&filter=-@home to pick by project and context
+//	POST /activities                add {"timestamp": "2014-01-01 12:00 Europe/Berlin", "command_tag": "", "body": "Wash the car"}
+//	POST /activities/ID/done        mark as done
+//	POST /activities/ID/cancel      mark as cancelled, never to be done
+//	POST /activities/ID/delay       delay by {"count": 1, "unit": "day"}
+//	POST /activities/ID/reschedule  move to {"timestamp": "2014-08-19 11:00"}
+//	POST /activities/ID/edit        change the text to {"text": "!1 Wash the car"}, keeping the ID
+//	GET  /activities/ID/history     the lines of the log that mention ID
+//
+// Every POST must be sent as application/json. If ACTS_TOKEN is set then
+// every request to /activities must carry "Authorization: Bearer TOKEN".
 func main() {
-	cmdToFunc := map[string]func([]string){
-		"new":    newItem,
-		"add":    newItem,
-		"done":   doneItem,
-		"del":    doneItem,
-		"delete": doneItem,
-		"delay":  delayItem,
-		"grep":   grepItems,
-		"get":    getActivity,
-		"help":   help,
+	addr := flag.String("addr", "127.0.0.1:8080", "address to listen on")
+	flag.Parse()
+	if err := entities.ConfigureZone(os.Getenv("ACTS_TZ")); err != nil {
+		log.Fatal(err)
 	}
-	if len(os.Args) < 2 {
-		help([]string{})
-	} else if function, ok := cmdToFunc[os.Args[1]]; ok {
-		function(os.Args[2:])
-	} else {
-		help([]string{})
+	opened, err := openStore()
+	if err != nil {
+		log.Fatal(err)
 	}
+	store = opened
+
+	token := os.Getenv("ACTS_TOKEN")
+	http.HandleFunc("/", frontEnd)
+	http.HandleFunc("/activities", guard(token, activities))
+	http.HandleFunc("/activities/", guard(token, oneActivity))
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+var store usecases.Store
+
+func getStore() usecases.Store {
+	return store
 }
 
-func getLogfile() boundaries.Logfile {
-	if os.Getenv("ACTS_LOGFILE") != "" {
-		return boundaries.Logfile{os.Getenv("ACTS_LOGFILE")}
+// openStore opens the storage named by ACTS_STORE, or else the logfile
+// named by ACTS_LOGFILE, or else logfile.txt. It is opened once and shared
+// by every request, so a mem:// store lasts as long as the server.
+func openStore() (usecases.Store, error) {
+	location := os.Getenv("ACTS_STORE")
+	if location == "" {
+		location = os.Getenv("ACTS_LOGFILE")
+	}
+	if location == "" {
+		location = "logfile.txt"
 	}
-	return boundaries.Logfile{"logfile.txt"}
+	return boundaries.Open(location)
 }
 
-func newItem(args []string) {
-	if doesNotPassTheCheck(args) {
+//go:embed index.html
+var indexHTML []byte
+
+func frontEnd(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		writeStatus(w, http.StatusNotFound, fmt.Errorf("No such resource %s", r.URL.Path))
 		return
 	}
-	wholeInput := concatenate(args)
-
-	activity, err := entities.ParseOneActivity(wholeInput)
-	if err != nil {
-		fmt.Println("You probably meant to say 'new now'")
+	if r.Method != "GET" {
+		methodNotAllowed(w, "GET")
 		return
 	}
-	fmt.Println(usecases.AddItem(activity, getLogfile()))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexHTML)
 }
 
-func concatenate(args []string) string {
-	var buffer bytes.Buffer
-	for i := 0; i < len(args); i++ {
-		if i > 0 {
-			buffer.WriteString(" ")
-		}
-		buffer.WriteString(args[i])
-	}
-	return buffer.String()
+type activityJSON struct {
+	Id         string    `json:"id"`
+	ShortId    string    `json:"short_id"`
+	Timestamp  time.Time `json:"timestamp"`
+	When       string    `json:"when"`
+	CommandTag string    `json:"command_tag,omitempty"`
+	Priority   int       `json:"priority,omitempty"`
+	Body       string    `json:"body"`
+	Projects   []string  `json:"projects"`
+	Contexts   []string  `json:"contexts"`
 }
 
-func doesNotPassTheCheck(args []string) bool {
-	if isNowCommand(args) {
-		handleNowCommand(args)
-		return true
-	}
-	if isTodayCommand(args) {
-		handleTodayCommand(args)
-		return true
-	}
-	if len(args) < 3 {
-		fmt.Println("Arguments to newItem were only:", args)
-		help(args)
-		return true
-	}
-	return false
+type newActivityJSON struct {
+	Timestamp  string `json:"timestamp"`
+	CommandTag string `json:"command_tag"`
+	Priority   int    `json:"priority"`
+	Body       string `json:"body"`
+}
+
+type delayJSON struct {
+	Count int    `json:"count"`
+	Unit  string `json:"unit"`
+}
+
+type rescheduleJSON struct {
+	Timestamp string `json:"timestamp"`
 }
 
-func isNowCommand(args []string) bool {
-	return len(args) > 1 && args[0] == "now"
+type editJSON struct {
+	Text string `json:"text"`
 }
-func handleNowCommand(args []string) {
-	now := time.Now()
-	newargs := todayWithTime(args, now.Format("15:04"))
-	newItem(newargs)
+
+type idJSON struct {
+	Id string `json:"id,omitempty"`
+}
+
+type historyJSON struct {
+	Lines []string `json:"lines"`
 }
 
-func isTodayCommand(args []string) bool {
-	_, err := time.Parse("15:04", args[0])
-	return err == nil
+type errorJSON struct {
+	Error string `json:"error"`
 }
-func handleTodayCommand(args []string) {
-	newargs := todayWithTime(args, args[0])
-	newItem(newargs)
+
+type ambiguousJSON struct {
+	Error      string         `json:"error"`
+	Candidates []activityJSON `json:"candidates"`
 }
 
-func todayWithTime(args []string, timestamp string) []string {
-	now := time.Now()
-	newargs := make([]string, len(args)+1)
-	newargs[0] = now.Format("2006-01-02")
-	newargs[1] = timestamp
-	for i := 2; i < len(args)+1; i++ {
-		newargs[i] = args[i-1]
+func activities(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		getActivity(w, r)
+	case "POST":
+		newItem(w, r)
+	default:
+		methodNotAllowed(w, "GET, POST")
 	}
-	return newargs
 }
 
-func getActivity(args []string) {
-	items := usecases.GetActivity(getLogfile())
-	for _, el := range items {
-		fmt.Println(el)
+// guard wraps the handlers of /activities. When the token is not empty,
+// a request without it as "Authorization: Bearer TOKEN" is refused. A POST
+// must say that its body is application/json: a web page on another site
+// can only send that after asking the server first, which it never agrees
+// to, so other sites cannot change the activities.
+func guard(token string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && !hasToken(r, token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="acts"`)
+			writeStatus(w, http.StatusUnauthorized, fmt.Errorf("Send the ACTS_TOKEN as 'Authorization: Bearer TOKEN'"))
+			return
+		}
+		if r.Method == "POST" && !isJSON(r) {
+			writeStatus(w, http.StatusUnsupportedMediaType, fmt.Errorf("Send the body as application/json"))
+			return
+		}
+		handler(w, r)
 	}
 }
 
-func doneItem(args []string) {
-	if len(args) < 1 {
-		fmt.Println("Arguments to doneItem were only:", args)
-		help(args)
+func hasToken(r *http.Request, token string) bool {
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func isJSON(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+// oneActivity dispatches /activities/ID/ACTION
+func oneActivity(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/activities/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		writeStatus(w, http.StatusNotFound, fmt.Errorf("No such resource %s", r.URL.Path))
 		return
 	}
-	err := usecases.MarkActivityAsDone(args[0], getLogfile())
-	if err != nil {
-		fmt.Print(err)
+	id, action := parts[0], parts[1]
+
+	cmdToFunc := map[string]struct {
+		method   string
+		function func(http.ResponseWriter, *http.Request, string)
+	}{
+		"done":       {"POST", doneItem},
+		"cancel":     {"POST", cancelItem},
+		"delay":      {"POST", delayItem},
+		"reschedule": {"POST", rescheduleItem},
+		"edit":       {"POST", editItem},
+		"history":    {"GET", grepItems},
 	}
+	handler, ok := cmdToFunc[action]
+	if !ok {
+		writeStatus(w, http.StatusNotFound, fmt.Errorf("No such resource %s", r.URL.Path))
+		return
+	}
+	if r.Method != handler.method {
+		methodNotAllowed(w, handler.method)
+		return
+	}
+	handler.function(w, r, id)
 }
 
-func grepItems(args []string) {
-	if len(args) < 1 {
-		fmt.Println("Arguments to doneItem were only:", args)
-		help(args)
+func getActivity(w http.ResponseWriter, r *http.Request) {
+	var clock entities.Clock = entities.SystemClock{}
+	if at := r.URL.Query().Get("at"); at != "" {
+		stamp, err := entities.ParseTimestamp(strings.Replace(at, "T", " ", 1))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		clock = entities.FixedClock{Time: stamp}
+	}
+	filter, err := entities.ParseFilter(filterWords(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	order := r.URL.Query().Get("sort")
+	if order == "" {
+		order = "time"
+	}
+	due, err := usecases.DueActivities(filter, order, getStore(), clock)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
-	grepped := usecases.GrepItems("("+args[0], getLogfile())
-	if grepped != nil {
-		for _, el := range grepped {
-			fmt.Println(el)
+	output := []activityJSON{}
+	for _, activity := range due {
+		output = append(output, toJSON(activity))
+	}
+	writeJSON(w, http.StatusOK, output)
+}
+
+// filterWords are the ?filter= parameters, such as "+work" and "-@home".
+// A '+' that was not written as %2B arrives as a space, and is put back.
+func filterWords(r *http.Request) []string {
+	words := []string{}
+	for _, word := range r.URL.Query()["filter"] {
+		if strings.HasPrefix(word, " ") {
+			word = "+" + word[1:]
+		}
+		if strings.HasPrefix(word, "- ") {
+			word = "-+" + word[2:]
 		}
+		words = append(words, word)
 	}
+	return words
 }
 
-func delayItem(args []string) {
-	//delay ID count unit ('hours' 'days')
-	if len(args) < 1 {
-		fmt.Println("Arguments to delayItem were only:", args)
-		help(args)
+func newItem(w http.ResponseWriter, r *http.Request) {
+	var input newActivityJSON
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, err)
+		return
+	}
+	when, err := entities.ParseMoment(input.Timestamp, time.Now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	text := input.Body
+	if input.Priority < 0 || input.Priority > 3 {
+		writeError(w, fmt.Errorf("Priority %d not found. Legal priorities are 1 to 3, or 0 for none", input.Priority))
 		return
 	}
-	if len(args) == 1 {
-		if err := usecases.DelayActivity(args[0], 1, "day", getLogfile()); err != nil {
-			fmt.Print(err)
+	if input.Priority != 0 {
+		text = fmt.Sprintf("!%d %s", input.Priority, text)
+	}
+	if input.CommandTag != "" {
+		if strings.ContainsFunc(input.CommandTag, unicode.IsSpace) {
+			writeError(w, fmt.Errorf("Repeat command '%s' may not contain spaces", input.CommandTag))
+			return
+		}
+		if _, err := entities.ParseRepeatRule(input.CommandTag); err != nil {
+			writeError(w, err)
+			return
 		}
+		text = fmt.Sprintf("@rtask:%s %s", input.CommandTag, text)
+	}
+	activity := entities.OneActivity{Timestamp: when}.WithText(text)
+	hash, err := usecases.AddItem(activity, getStore())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, idJSON{hash})
+}
+
+func doneItem(w http.ResponseWriter, r *http.Request, id string) {
+	hash, err := usecases.MarkActivityAsDone(id, getStore(), entities.SystemClock{})
+	if err != nil {
+		writeError(w, err)
 		return
 	}
-	if len(args) == 2 {
-		fmt.Println("Arguments to delayItem were only:", args)
-		help(args)
+	writeJSON(w, http.StatusOK, idJSON{hash})
+}
+
+func cancelItem(w http.ResponseWriter, r *http.Request, id string) {
+	if err := usecases.CancelActivity(id, getStore()); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, idJSON{})
+}
+
+func delayItem(w http.ResponseWriter, r *http.Request, id string) {
+	input := delayJSON{1, "day"}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		writeError(w, err)
 		return
 	}
-	count, err := strconv.Atoi(args[1])
+	hash, err := usecases.DelayActivity(id, input.Count, input.Unit, getStore())
 	if err != nil {
-		fmt.Print(err)
+		writeError(w, err)
+		return
 	}
-	if err = usecases.DelayActivity(args[0], count, args[2], getLogfile()); err != nil {
-		fmt.Print(err)
+	writeJSON(w, http.StatusOK, idJSON{hash})
+}
+
+func rescheduleItem(w http.ResponseWriter, r *http.Request, id string) {
+	var input rescheduleJSON
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, err)
+		return
 	}
+	stamp, err := entities.ParseMoment(input.Timestamp, time.Now())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	hash, err := usecases.RescheduleActivity(id, stamp, getStore())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, idJSON{hash})
+}
+
+func editItem(w http.ResponseWriter, r *http.Request, id string) {
+	var input editJSON
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, err)
+		return
+	}
+	hash, err := usecases.EditActivity(id, usecases.NewText(input.Text), getStore())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, idJSON{hash})
 }
 
-func parseActivity(datebit, timebit, body string) (entities.OneActivity, error) {
-	loc, _ := time.LoadLocation("Australia/Melbourne")
-	stamp, err := time.ParseInLocation("2006-01-02 15:04", fmt.Sprintf("%s %s", datebit, timebit), loc)
+func grepItems(w http.ResponseWriter, r *http.Request, id string) {
+	grepped, err := usecases.GrepItems("("+id, getStore())
 	if err != nil {
-		return entities.OneActivity{}, err
+		writeError(w, err)
+		return
 	}
-	return entities.OneActivity{"", stamp, "", body}, nil
+	writeJSON(w, http.StatusOK, historyJSON{grepped})
+}
+
+func toJSON(activity entities.OneActivity) activityJSON {
+	return activityJSON{
+		Id:         activity.Id,
+		ShortId:    activity.DisplayId(),
+		Timestamp:  activity.Timestamp,
+		When:       activity.TimeString(),
+		CommandTag: activity.CommandTag,
+		Priority:   activity.Priority,
+		Body:       activity.Body,
+		Projects:   activity.Projects(),
+		Contexts:   activity.Contexts(),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError chooses the HTTP status from the type of the error
+func writeError(w http.ResponseWriter, err error) {
+	var notFound *usecases.NotFoundError
+	var ambiguous *usecases.AmbiguousIdError
+	var parseError *boundaries.ParseError
+	var ioError *boundaries.IOError
+	var conflict *boundaries.ConflictError
+	switch {
+	case errors.As(err, &notFound):
+		writeStatus(w, http.StatusNotFound, err)
+	case errors.As(err, &ambiguous):
+		candidates := []activityJSON{}
+		for _, activity := range ambiguous.Candidates {
+			candidates = append(candidates, toJSON(activity))
+		}
+		writeJSON(w, http.StatusConflict, ambiguousJSON{"Ambiguous ID " + ambiguous.Id, candidates})
+	case errors.As(err, &conflict):
+		writeStatus(w, http.StatusConflict, err)
+	case errors.As(err, &parseError), errors.As(err, &ioError):
+		writeStatus(w, http.StatusInternalServerError, err)
+	default:
+		writeStatus(w, http.StatusBadRequest, err)
+	}
+}
+
+func writeStatus(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorJSON{strings.TrimSpace(err.Error())})
 }
 
-func help(args []string) {
-	fmt.Printf(`%s cmd [args]
-    help
-    get
-    new [date] [time] [body]
-    done [ID]
-    grep [ID]
-    delay [ID] [count] [unit] ('minutes' 'hours' 'days' 'weeks' 'months')
-`, os.Args[0])
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeStatus(w, http.StatusMethodNotAllowed, fmt.Errorf("Method not allowed, use %s", allowed))
 }
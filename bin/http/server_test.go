@@ -0,0 +1,94 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Fepelus/ActivityStream/boundaries"
+)
+
+func TestGuard(t *testing.T) {
+	tests := []struct {
+		token       string
+		method      string
+		contentType string
+		auth        string
+		want        int
+	}{
+		{"", "POST", "application/json", "", http.StatusCreated},
+		{"", "POST", "application/json; charset=utf-8", "", http.StatusCreated},
+		{"", "POST", "text/plain", "", http.StatusUnsupportedMediaType},
+		{"", "POST", "application/x-www-form-urlencoded", "", http.StatusUnsupportedMediaType},
+		{"", "POST", "", "", http.StatusUnsupportedMediaType},
+		{"", "GET", "", "", http.StatusOK},
+		{"sesame", "GET", "", "", http.StatusUnauthorized},
+		{"sesame", "GET", "", "Bearer wrong", http.StatusUnauthorized},
+		{"sesame", "GET", "", "sesame", http.StatusUnauthorized},
+		{"sesame", "GET", "", "Bearer sesame", http.StatusOK},
+		{"sesame", "POST", "application/json", "", http.StatusUnauthorized},
+		{"sesame", "POST", "application/json", "Bearer sesame", http.StatusCreated},
+	}
+	for _, test := range tests {
+		store = boundaries.MemoryStore()
+		body := ""
+		if test.method == "POST" {
+			body = `{"timestamp": "2014-01-01 12:00", "body": "Wash the car"}`
+		}
+		request := httptest.NewRequest(test.method, "/activities", strings.NewReader(body))
+		request.Header.Set("Origin", "http://evil.example")
+		if test.contentType != "" {
+			request.Header.Set("Content-Type", test.contentType)
+		}
+		if test.auth != "" {
+			request.Header.Set("Authorization", test.auth)
+		}
+		response := httptest.NewRecorder()
+		guard(test.token, activities)(response, request)
+		if response.Code != test.want {
+			t.Errorf("%+v: got %d %s, want %d", test, response.Code, response.Body, test.want)
+		}
+		all, _ := store.GetAll()
+		if stored := len(all) == 1; stored != (test.want == http.StatusCreated) {
+			t.Errorf("%+v: stored %v", test, all)
+		}
+	}
+}
+
+func TestNewItemChecksTheRepeatCommand(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want int
+	}{
+		{"every-n-days:2", http.StatusCreated},
+		{"bogus tag", http.StatusBadRequest},
+		{"every-n-days:2 extra", http.StatusBadRequest},
+		{"every-n-days:2\tx", http.StatusBadRequest},
+		{"every-2-days", http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		store = boundaries.MemoryStore()
+		body := `{"timestamp": "2014-01-01 12:00", "command_tag": "` + test.tag + `", "body": "x"}`
+		request := httptest.NewRequest("POST", "/activities", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		response := httptest.NewRecorder()
+		activities(response, request)
+		if response.Code != test.want {
+			t.Errorf("%q: got %d %s, want %d", test.tag, response.Code, response.Body, test.want)
+		}
+		all, _ := store.GetAll()
+		if test.want == http.StatusCreated && (len(all) != 1 || all[0].CommandTag != test.tag || all[0].Body != "x") {
+			t.Errorf("%q: stored %+v", test.tag, all)
+		}
+		if test.want != http.StatusCreated && len(all) != 0 {
+			t.Errorf("%q: stored %+v, want nothing", test.tag, all)
+		}
+	}
+}
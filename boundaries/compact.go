@@ -0,0 +1,170 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Compact rewrites the journal so that it holds only the ADD lines of the
+// activities that have not been deleted, each as it was last edited, as
+// one "COMPACT" transaction that
+// Undo will not go past. It returns how many lines of activities were
+// removed. If archive is true then the removed lines are first kept aside
+// by the backend, in a side file named for today's date for those kept in
+// files, and where they went is returned.
+//
+// The backend replaces the journal while the exclusive lock is held, so a
+// reader sees either the whole old journal or the whole new one.
+func (this *Store) Compact(archive bool) (int, string, error) {
+	unlock, err := this.journal.lock(true)
+	if err != nil {
+		return 0, "", err
+	}
+	defer unlock()
+
+	transactions := []transaction{}
+	liveAt := map[string][2]int{}
+	err = this.journal.readTransactions(func(t transaction) error {
+		for i, thisline := range t.Lines {
+			switch thisline.Command {
+			case "ADD":
+				liveAt[thisline.Id] = [2]int{len(transactions), i}
+			case "EDIT":
+				if _, ok := liveAt[thisline.Id]; ok {
+					liveAt[thisline.Id] = [2]int{len(transactions), i}
+				}
+			}
+			if thisline.removes() {
+				delete(liveAt, thisline.Id)
+			}
+		}
+		transactions = append(transactions, t)
+		return nil
+	})
+	if err != nil {
+		return 0, "", err
+	}
+
+	kept := map[[2]int]bool{}
+	for _, at := range liveAt {
+		kept[at] = true
+	}
+	now := this.now()
+	keptLines := []LogLine{}
+	removedLines := []string{}
+	removed := 0
+	for i, t := range transactions {
+		if header, ok := t.header(); ok {
+			removedLines = append(removedLines, strings.TrimSuffix(header.LogString(), "\n"))
+		}
+		for j, thisline := range t.Lines {
+			if kept[[2]int{i, j}] {
+				thisline.Command = "ADD"
+				keptLines = append(keptLines, thisline)
+				continue
+			}
+			removedLines = append(removedLines, strings.TrimSuffix(thisline.LogString(), "\n"))
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, "", nil
+	}
+	replacement := []transaction{}
+	if len(keptLines) > 0 {
+		replacement = append(replacement, newTransaction(compactOperation, keptLines...))
+	}
+
+	archiveName := ""
+	if archive {
+		archiveName, err = this.journal.archive(removedLines, now)
+		if err != nil {
+			return 0, "", err
+		}
+	}
+	if err := this.journal.replaceWith(replacement); err != nil {
+		return 0, "", err
+	}
+	return removed, archiveName, nil
+}
+
+// archive appends the lines to a side file named for the date
+func (this Logfile) archive(lines []string, now time.Time) (string, error) {
+	archiveName := archiveFilename(this.Filename, now)
+	return archiveName, appendLines(archiveName, lines)
+}
+
+func archiveFilename(filename string, now time.Time) string {
+	return fmt.Sprintf("%s.%s.archive", filename, now.Format("2006-01-02"))
+}
+
+func appendLines(filename string, lines []string) error {
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return &IOError{"append to", filename, err}
+	}
+	if err := writeLines(f, lines); err != nil {
+		f.Close()
+		return &IOError{"append to", filename, err}
+	}
+	if err := f.Close(); err != nil {
+		return &IOError{"append to", filename, err}
+	}
+	return nil
+}
+
+// replaceWith atomically replaces the logfile with one holding the given
+// transactions
+func (this Logfile) replaceWith(kept []transaction) error {
+	lines := []string{}
+	for _, t := range kept {
+		lines = append(lines, t.LogStrings()...)
+	}
+	dir, base := filepath.Split(this.Filename)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, base+".*.tmp")
+	if err != nil {
+		return &IOError{"rewrite", this.Filename, err}
+	}
+	tempName := f.Name()
+	if err := writeLines(f, lines); err != nil {
+		f.Close()
+		os.Remove(tempName)
+		return &IOError{"rewrite", this.Filename, err}
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tempName)
+		return &IOError{"rewrite", this.Filename, err}
+	}
+	if err := os.Rename(tempName, this.Filename); err != nil {
+		os.Remove(tempName)
+		return &IOError{"rewrite", this.Filename, err}
+	}
+	return nil
+}
+
+// writeLines writes the lines and waits for them to reach the disk
+func writeLines(f *os.File, lines []string) error {
+	writer := bufio.NewWriter(f)
+	for _, line := range lines {
+		if _, err := writer.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
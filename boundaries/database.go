@@ -0,0 +1,124 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/boundaries/kv"
+)
+
+// database is the journal kept in an embedded key/value store, one
+// transaction to each key. The keys are "txn/" and a sequence number, and
+// each value is the transaction in the logfile format.
+type database struct {
+	Filename string
+	db       *kv.DB
+}
+
+const transactionKeyPrefix = "txn/"
+
+// DatabaseStore keeps the activities in the key/value store in the named
+// file, creating it if need be
+func DatabaseStore(filename string) (*Store, error) {
+	db, err := kv.Open(filename)
+	if err != nil {
+		return nil, &IOError{"open", filename, err}
+	}
+	return &Store{journal: &database{Filename: filename, db: db}}, nil
+}
+
+// lock takes the lock on a file beside the database, as Logfile does, and
+// then catches up with what other processes have written.
+func (this *database) lock(exclusive bool) (func(), error) {
+	unlock, err := lockFile(this.Filename+".lock", exclusive)
+	if err != nil {
+		return nil, err
+	}
+	if err := this.db.Refresh(); err != nil {
+		unlock()
+		return nil, &IOError{"read", this.Filename, err}
+	}
+	return unlock, nil
+}
+
+func (this *database) readTransactions(each func(transaction) error) error {
+	for _, key := range this.db.Keys(transactionKeyPrefix) {
+		value, _ := this.db.Get(key)
+		reader := transactionReader{each: each}
+		for i, line := range strings.Split(strings.TrimSuffix(string(value), "\n"), "\n") {
+			thisline, err := parseLine(line, this.Filename+"#"+key, i+1)
+			if err != nil {
+				return err
+			}
+			if err := reader.add(thisline); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (this *database) appendTransaction(t transaction) error {
+	next := uint64(1)
+	if keys := this.db.Keys(transactionKeyPrefix); len(keys) > 0 {
+		last, err := strconv.ParseUint(strings.TrimPrefix(keys[len(keys)-1], transactionKeyPrefix), 16, 64)
+		if err != nil {
+			return &ParseError{Filename: this.Filename, Input: keys[len(keys)-1], Err: err}
+		}
+		next = last + 1
+	}
+	batch := kv.Batch{}
+	batch.Put(transactionKey(next), transactionValue(t))
+	if err := this.db.Write(&batch); err != nil {
+		return &IOError{"append to", this.Filename, err}
+	}
+	return nil
+}
+
+// replaceWith deletes every transaction and puts the kept ones in their
+// place in a single batch, then has the store drop what it no longer needs.
+func (this *database) replaceWith(kept []transaction) error {
+	batch := kv.Batch{}
+	for _, key := range this.db.Keys(transactionKeyPrefix) {
+		batch.Delete(key)
+	}
+	for i, t := range kept {
+		batch.Put(transactionKey(uint64(i+1)), transactionValue(t))
+	}
+	if err := this.db.Write(&batch); err != nil {
+		return &IOError{"rewrite", this.Filename, err}
+	}
+	if err := this.db.Compact(); err != nil {
+		return &IOError{"rewrite", this.Filename, err}
+	}
+	return nil
+}
+
+// archive appends the lines to a side file named for the date, as
+// Logfile does
+func (this *database) archive(lines []string, now time.Time) (string, error) {
+	archiveName := archiveFilename(this.Filename, now)
+	return archiveName, appendLines(archiveName, lines)
+}
+
+func (this *database) close() error {
+	return this.db.Close()
+}
+
+// transactionKey is zero-padded so that the keys sort in the order the
+// transactions were written
+func transactionKey(sequence uint64) string {
+	return fmt.Sprintf("%s%016x", transactionKeyPrefix, sequence)
+}
+
+func transactionValue(t transaction) []byte {
+	return []byte(strings.Join(t.LogStrings(), "\n") + "\n")
+}
@@ -0,0 +1,53 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import "fmt"
+
+// ParseError is returned when a line of the logfile cannot be understood.
+// Line counts from 1; it is 0 when the input did not come from a file.
+type ParseError struct {
+	Filename string
+	Line     int
+	Input    string
+	Err      error
+}
+
+func (this *ParseError) Error() string {
+	if this.Line == 0 {
+		return fmt.Sprintf("Could not parse log line %q: %s", this.Input, this.Err)
+	}
+	return fmt.Sprintf("%s:%d: could not parse log line %q: %s", this.Filename, this.Line, this.Input, this.Err)
+}
+
+func (this *ParseError) Unwrap() error { return this.Err }
+
+// IOError is returned when the logfile cannot be read or written.
+// Op is what was being attempted: "open", "read", "append to", "rewrite"
+// or "lock".
+type IOError struct {
+	Op       string
+	Filename string
+	Err      error
+}
+
+func (this *IOError) Error() string {
+	return fmt.Sprintf("Could not %s %s: %s", this.Op, this.Filename, this.Err)
+}
+
+func (this *IOError) Unwrap() error { return this.Err }
+
+// ConflictError is returned when a change is asked of an activity that is
+// no longer in the stream, as when two commands given at the same time both
+// take out the same activity. Nothing is written.
+type ConflictError struct {
+	Id string
+}
+
+func (this *ConflictError) Error() string {
+	return fmt.Sprintf("Activity %s is no longer there; it may have just been done, cancelled or delayed elsewhere. Nothing has been changed.", this.Id)
+}
@@ -0,0 +1,37 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+// storedForm is an activity read from another program's file as the
+// storage would read it back, so that it can be compared with those
+// already stored
+func storedForm(activity entities.OneActivity) (entities.OneActivity, error) {
+	return entities.ParseOneActivity(activity.FullString())
+}
+
+// idPattern is what an activity's ID looks like, as another program keeps it
+var idPattern = regexp.MustCompile("^[0-9a-f]+$")
+
+// zoneToWrite is the name of the activity's zone for a format that has no
+// zones of its own, or "" if it is the configured Zone and need not be written
+func zoneToWrite(activity entities.OneActivity) string {
+	name := activity.Timestamp.Location().String()
+	if name == entities.Zone.String() {
+		return ""
+	}
+	if name == "UTC" || strings.Contains(name, "/") {
+		return name
+	}
+	return activity.Timestamp.Format("-07:00")
+}
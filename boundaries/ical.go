@@ -0,0 +1,386 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+// The activities are exchanged with calendar programs as iCalendar
+// (RFC 5545). Each activity is a VTODO that is DUE at its timestamp. A
+// repeating activity also has an RRULE, which calendars understand, and
+// its rule exactly as acts keeps it in X-ACTS-RTASK, since an 'after' rule
+// has no RRULE of its own. Each zone named by a TZID is written out as a
+// VTIMEZONE, with the changes of offset in the years the activities span.
+
+const icalDate = "20060102T150405"
+
+// icalPriorities are the iCalendar PRIORITY, from 1 for the highest to 9
+// for the lowest, given to the priorities of activities
+var icalPriorities = map[int]int{1: 1, 2: 5, 3: 9}
+
+var icalFrequencies = map[string]string{
+	"hours":  "HOURLY",
+	"days":   "DAILY",
+	"weeks":  "WEEKLY",
+	"months": "MONTHLY",
+}
+
+// WriteICalendar writes the activities as a calendar of VTODOs. now is
+// written as the time the calendar was made.
+func WriteICalendar(w io.Writer, activities entities.Activities, now time.Time) error {
+	writer := bufio.NewWriter(w)
+	write := func(line string) {
+		writer.WriteString(foldICalLine(line))
+	}
+	write("BEGIN:VCALENDAR")
+	write("VERSION:2.0")
+	write("PRODID:-//Fepelus//ActivityStream//EN")
+	for _, zone := range icalZones(activities) {
+		for _, line := range zone.lines() {
+			write(line)
+		}
+	}
+	for _, activity := range activities {
+		write("BEGIN:VTODO")
+		write("UID:" + activity.Id + "@activitystream")
+		write("DTSTAMP:" + now.UTC().Format(icalDate) + "Z")
+		write("DUE" + icalTime(activity.Timestamp))
+		write("SUMMARY:" + escapeICalText(activity.Body))
+		if priority, ok := icalPriorities[activity.Priority]; ok {
+			write(fmt.Sprintf("PRIORITY:%d", priority))
+		}
+		if activity.HasRepeatCommand() {
+			if rule, err := entities.ParseRepeatRule(activity.CommandTag); err == nil {
+				write(fmt.Sprintf("RRULE:FREQ=%s;INTERVAL=%d", icalFrequencies[rule.Unit], rule.Count))
+			}
+			write("X-ACTS-RTASK:" + activity.CommandTag)
+		}
+		write("END:VTODO")
+	}
+	write("END:VCALENDAR")
+	return writer.Flush()
+}
+
+// icalTime is the parameters and value of a DUE property: in UTC unless the
+// timestamp is in a zone from the time zone database, which is then named
+func icalTime(stamp time.Time) string {
+	name := stamp.Location().String()
+	if strings.Contains(name, "/") {
+		return fmt.Sprintf(";TZID=%s:%s", name, stamp.Format(icalDate))
+	}
+	return ":" + stamp.UTC().Format(icalDate) + "Z"
+}
+
+// an icalZone is a zone named by a TZID and the span of the timestamps
+// that are in it
+type icalZone struct {
+	loc      *time.Location
+	from, to time.Time
+}
+
+// icalZones are the zones that icalTime names, in the order they are first
+// used
+func icalZones(activities entities.Activities) []*icalZone {
+	zones := []*icalZone{}
+	byName := map[string]*icalZone{}
+	for _, activity := range activities {
+		stamp := activity.Timestamp
+		name := stamp.Location().String()
+		if !strings.Contains(name, "/") {
+			continue
+		}
+		zone, ok := byName[name]
+		if !ok {
+			zone = &icalZone{loc: stamp.Location(), from: stamp, to: stamp}
+			byName[name] = zone
+			zones = append(zones, zone)
+		}
+		if stamp.Before(zone.from) {
+			zone.from = stamp
+		}
+		if stamp.After(zone.to) {
+			zone.to = stamp
+		}
+	}
+	return zones
+}
+
+// lines is the VTIMEZONE: one STANDARD or DAYLIGHT for each offset the
+// zone keeps from the start of the first year of its span to the end of
+// the last, each starting at the local time it began
+func (this icalZone) lines() []string {
+	lines := []string{"BEGIN:VTIMEZONE", "TZID:" + this.loc.String()}
+	stamp := time.Date(this.from.In(this.loc).Year(), 1, 1, 0, 0, 0, 0, this.loc)
+	end := time.Date(this.to.In(this.loc).Year()+1, 1, 1, 0, 0, 0, 0, this.loc)
+	for stamp.Before(end) {
+		name, offset := stamp.Zone()
+		start, next := stamp.ZoneBounds()
+		before := offset
+		onset := "19700101T000000"
+		if !start.IsZero() {
+			_, before = start.Add(-time.Second).Zone()
+			onset = start.In(time.FixedZone("", before)).Format(icalDate)
+		}
+		kind := "STANDARD"
+		if stamp.IsDST() {
+			kind = "DAYLIGHT"
+		}
+		lines = append(lines,
+			"BEGIN:"+kind,
+			"DTSTART:"+onset,
+			"TZOFFSETFROM:"+icalOffset(before),
+			"TZOFFSETTO:"+icalOffset(offset),
+			"TZNAME:"+name,
+			"END:"+kind)
+		if next.IsZero() {
+			break
+		}
+		stamp = next
+	}
+	return append(lines, "END:VTIMEZONE")
+}
+
+// icalOffset is an offset east of UTC as "+hhmm", or "+hhmmss" if it is
+// not a whole number of minutes
+func icalOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	offset := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		offset += fmt.Sprintf("%02d", seconds%60)
+	}
+	return offset
+}
+
+// foldICalLine ends the line with CRLF, first breaking it so that no line
+// is longer than 75 bytes, without breaking a character
+func foldICalLine(line string) string {
+	var output strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		output.WriteString(line[0:cut] + "\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	output.WriteString(line + "\r\n")
+	return output.String()
+}
+
+func escapeICalText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(text)
+}
+
+// unescapeICalText also turns line breaks into spaces, as an activity is
+// a single line
+func unescapeICalText(text string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, " ", `\N`, " ").Replace(text)
+}
+
+// an icalProperty is one content line: its name, its parameters and its value
+type icalProperty struct {
+	params map[string]string
+	value  string
+}
+
+// ReadICalendar reads the VTODOs and VEVENTs of a calendar as activities,
+// as the storage would read them back. Those that WriteICalendar wrote have
+// their ID again, from the UID; others have none. A VTODO is due at its
+// DUE, or else its DTSTART, and a VEVENT at its DTSTART; those with
+// neither, and those already completed or cancelled, are left out.
+func ReadICalendar(r io.Reader) (entities.Activities, error) {
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, err
+	}
+	output := entities.Activities{}
+	var component map[string]icalProperty
+	for i, line := range lines {
+		name, property, err := parseICalLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read calendar line %d %q: %s", i+1, line, err)
+		}
+		switch {
+		case name == "BEGIN" && (property.value == "VTODO" || property.value == "VEVENT"):
+			component = map[string]icalProperty{}
+		case name == "END" && (property.value == "VTODO" || property.value == "VEVENT"):
+			if component == nil {
+				continue
+			}
+			activity, ok, err := icalActivity(component)
+			if err != nil {
+				return nil, fmt.Errorf("Could not read the calendar item ending on line %d: %s", i+1, err)
+			}
+			if ok {
+				output = append(output, activity)
+			}
+			component = nil
+		case component != nil:
+			if _, seen := component[name]; !seen {
+				component[name] = property
+			}
+		}
+	}
+	return output, nil
+}
+
+func icalActivity(component map[string]icalProperty) (entities.OneActivity, bool, error) {
+	if status := strings.ToUpper(component["STATUS"].value); status == "COMPLETED" || status == "CANCELLED" {
+		return entities.OneActivity{}, false, nil
+	}
+	due, ok := component["DUE"]
+	if !ok {
+		due, ok = component["DTSTART"]
+	}
+	if !ok {
+		return entities.OneActivity{}, false, nil
+	}
+	stamp, err := parseICalTime(due)
+	if err != nil {
+		return entities.OneActivity{}, false, err
+	}
+	activity := entities.OneActivity{
+		Timestamp: stamp,
+		Body:      unescapeICalText(component["SUMMARY"].value),
+	}
+	if priority, err := strconv.Atoi(component["PRIORITY"].value); err == nil && priority > 0 {
+		activity.Priority = 1 + (priority-1)/3
+	}
+	if tag, ok := component["X-ACTS-RTASK"]; ok {
+		activity.CommandTag = tag.value
+	} else if rrule, ok := component["RRULE"]; ok {
+		activity.CommandTag = repeatTagFromRRule(rrule.value)
+	}
+
+	stored, err := storedForm(activity)
+	if id, ok := strings.CutSuffix(component["UID"].value, "@activitystream"); ok && idPattern.MatchString(id) {
+		stored.Id = id
+	}
+	return stored, err == nil, err
+}
+
+// repeatTagFromRRule is the tag for a fixed-schedule rule such as
+// "FREQ=DAILY;INTERVAL=2", or "" for a rule that acts cannot follow: one
+// that ends, with COUNT or UNTIL, or picks its days, with a BY part
+func repeatTagFromRRule(value string) string {
+	parts := map[string]string{}
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		pair := strings.SplitN(part, "=", 2)
+		if len(pair) != 2 {
+			return ""
+		}
+		name := strings.ToUpper(pair[0])
+		if name != "FREQ" && name != "INTERVAL" && name != "WKST" {
+			return ""
+		}
+		parts[name] = pair[1]
+	}
+	count := 1
+	if interval, ok := parts["INTERVAL"]; ok {
+		n, err := strconv.Atoi(interval)
+		if err != nil || n < 1 {
+			return ""
+		}
+		count = n
+	}
+	if strings.ToUpper(parts["FREQ"]) == "YEARLY" {
+		return fmt.Sprintf("every-n-months:%d", count*12)
+	}
+	for unit, frequency := range icalFrequencies {
+		if strings.ToUpper(parts["FREQ"]) == frequency {
+			return fmt.Sprintf("every-n-%s:%d", unit, count)
+		}
+	}
+	return ""
+}
+
+// parseICalTime reads a date-time in UTC ("...Z"), in the zone named by a
+// TZID parameter, or otherwise in the configured Zone, or a date, which is
+// taken to start at midnight
+func parseICalTime(property icalProperty) (time.Time, error) {
+	loc := entities.Zone
+	if name, ok := property.params["TZID"]; ok {
+		zone, err := entities.LoadZone(strings.TrimPrefix(name, "/"))
+		if err != nil {
+			return time.Time{}, err
+		}
+		loc = zone
+	}
+	value := property.value
+	if strings.HasSuffix(value, "Z") {
+		stamp, err := time.Parse(icalDate, strings.TrimSuffix(value, "Z"))
+		return stamp.Truncate(time.Minute), err
+	}
+	if len(value) == len("20060102") {
+		return time.ParseInLocation("20060102", value, loc)
+	}
+	stamp, err := time.ParseInLocation(icalDate, value, loc)
+	return stamp.Truncate(time.Minute), err
+}
+
+// unfoldICalLines reads the content lines, joining each line that starts
+// with a space or tab to the one before it
+func unfoldICalLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lines := []string{}
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// parseICalLine splits "NAME;PARAM=VALUE;PARAM=\"VA:LUE\":value". The
+// names of the property and its parameters are upper-cased.
+func parseICalLine(line string) (string, icalProperty, error) {
+	quoted := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		}
+		if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", icalProperty{}, fmt.Errorf("no ':' between the name and the value")
+	}
+	fields := strings.Split(line[0:colon], ";")
+	property := icalProperty{params: map[string]string{}, value: line[colon+1:]}
+	for _, param := range fields[1:] {
+		if pair := strings.SplitN(param, "=", 2); len(pair) == 2 {
+			property.params[strings.ToUpper(pair[0])] = strings.Trim(pair[1], `"`)
+		}
+	}
+	return strings.ToUpper(fields[0]), property, nil
+}
@@ -0,0 +1,159 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+func TestICalendarRoundTrip(t *testing.T) {
+	entities.Zone = time.UTC
+	berlin, _ := entities.LoadZone("Europe/Berlin")
+	store := MemoryStore()
+	repeating := newActivity("2014-01-01 12:00", "Duolingo")
+	repeating.CommandTag = "after-n-days:1"
+	urgent := newActivity("2014-01-02 09:30", "Renew the passport; the old one, with photos")
+	urgent.Priority = 1
+	urgent.Timestamp = time.Date(2014, 1, 2, 9, 30, 0, 0, berlin)
+	for _, activity := range []entities.OneActivity{repeating, urgent, newActivity("2014-01-03 08:00", "Wash the car")} {
+		if _, err := store.AddNew(activity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	live, _ := store.GetAll()
+	live.Sort()
+
+	var buffer bytes.Buffer
+	if err := WriteICalendar(&buffer, live, newActivity("2014-01-01 00:00", "").Timestamp); err != nil {
+		t.Fatal(err)
+	}
+	written := buffer.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DUE:20140101T120000Z\r\n",
+		"RRULE:FREQ=DAILY;INTERVAL=1\r\n",
+		"X-ACTS-RTASK:after-n-days:1\r\n",
+		"DUE;TZID=Europe/Berlin:20140102T093000\r\n",
+		"BEGIN:VTIMEZONE\r\nTZID:Europe/Berlin\r\n" +
+			"BEGIN:STANDARD\r\nDTSTART:20131027T030000\r\nTZOFFSETFROM:+0200\r\nTZOFFSETTO:+0100\r\nTZNAME:CET\r\nEND:STANDARD\r\n" +
+			"BEGIN:DAYLIGHT\r\nDTSTART:20140330T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\nTZNAME:CEST\r\nEND:DAYLIGHT\r\n" +
+			"BEGIN:STANDARD\r\nDTSTART:20141026T030000\r\nTZOFFSETFROM:+0200\r\nTZOFFSETTO:+0100\r\nTZNAME:CET\r\nEND:STANDARD\r\n" +
+			"END:VTIMEZONE\r\n",
+		"PRIORITY:1\r\n",
+		`SUMMARY:Renew the passport\; the old one\, with photos`,
+	} {
+		if !strings.Contains(written, want) {
+			t.Errorf("got\n%s\nwant it to contain %q", written, want)
+		}
+	}
+
+	read, err := ReadICalendar(strings.NewReader(written))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != len(live) {
+		t.Fatalf("read %d activities, want %d", len(read), len(live))
+	}
+	for i := range live {
+		if read[i].FullString() != live[i].FullString() {
+			t.Errorf("read %q, want %q", read[i].FullString(), live[i].FullString())
+		}
+	}
+	sameIds(t, read, live)
+}
+
+func TestReadICalendarFromElsewhere(t *testing.T) {
+	entities.Zone = time.UTC
+	calendar := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/Berlin",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"DTSTART;TZID=\"Europe/Berlin\":20140105T180000",
+		"SUMMARY:A meeting whose title goes on for long enough that it has been fo",
+		" lded",
+		"RRULE:FREQ=WEEKLY;BYDAY=SU",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"DUE;VALUE=DATE:20140106",
+		"PRIORITY:5",
+		"SUMMARY:Pay rent\\nby transfer",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"DUE:20140107T120000Z",
+		"STATUS:COMPLETED",
+		"SUMMARY:Already done",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"SUMMARY:Whenever",
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n")
+	read, err := ReadICalendar(strings.NewReader(calendar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, activity := range read {
+		got = append(got, activity.FullString())
+	}
+	want := []string{
+		"2014-01-05 18:00 Europe/Berlin A meeting whose title goes on for long enough that it has been folded",
+		"2014-01-06 00:00 UTC !2 Pay rent by transfer",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := ReadICalendar(strings.NewReader("BEGIN:VTODO\r\nDUE:tomorrow\r\nEND:VTODO\r\n")); err == nil {
+		t.Error("got no error for a DUE that is not a time")
+	}
+}
+
+func TestRepeatTagFromRRule(t *testing.T) {
+	tests := []struct {
+		rrule string
+		want  string
+	}{
+		{"FREQ=DAILY", "every-n-days:1"},
+		{"FREQ=WEEKLY;INTERVAL=2", "every-n-weeks:2"},
+		{"freq=monthly;interval=3;wkst=MO", "every-n-months:3"},
+		{"FREQ=YEARLY", "every-n-months:12"},
+		{"FREQ=WEEKLY;BYDAY=SU", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", ""},
+		{"FREQ=DAILY;COUNT=5", ""},
+		{"FREQ=DAILY;UNTIL=20140201T000000Z", ""},
+		{"FREQ=DAILY;INTERVAL=0", ""},
+		{"FREQ=SECONDLY", ""},
+		{"FREQ=DAILY;", "every-n-days:1"},
+		{"FREQ=DAILY;INTERVAL", ""},
+	}
+	for _, test := range tests {
+		if got := repeatTagFromRRule(test.rrule); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.rrule, got, test.want)
+		}
+	}
+}
+
+func TestFoldICalLine(t *testing.T) {
+	line := "SUMMARY:" + strings.Repeat("ü", 60)
+	folded := foldICalLine(line)
+	for _, part := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+		if len(part) > 75 {
+			t.Errorf("got a line of %d bytes", len(part))
+		}
+	}
+	if unfolded, _ := unfoldICalLines(strings.NewReader(folded)); len(unfolded) != 1 || unfolded[0] != line {
+		t.Errorf("got %q back, want %q", unfolded, line)
+	}
+}
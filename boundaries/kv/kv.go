@@ -0,0 +1,384 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+// Package kv is a small embedded key/value store kept in a single file.
+//
+// Changes are only ever appended to the file, in batches. Each record is
+//
+//	kind (1 byte) | key length (uvarint) | value length (uvarint) | key | value | CRC-32 (4 bytes)
+//
+// where kind is 'P' to put a value or 'D' to delete it, and each batch ends
+// with a record of kind 'C'. A batch at the end of the file whose commit
+// record is missing, or whose last record fails its checksum or is followed
+// only by zeros, was not completely written: it is ignored and the next
+// Write cuts it off. A record anywhere else that fails its checks is
+// corruption, and the file is left alone. Compact rewrites the file to hold
+// only the current values.
+//
+// A DB may be shared between goroutines. It does not lock the file against
+// other processes; callers that share the file should take a lock and then
+// call Refresh before using the DB.
+package kv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	putRecord    = 'P'
+	deleteRecord = 'D'
+	commitRecord = 'C'
+)
+
+// keys and values longer than this are taken to be corruption
+const maxLength = 1 << 30
+
+var (
+	errTorn = errors.New("record not completely written")
+	errBad  = errors.New("record fails its checks")
+)
+
+// CorruptError is returned for a record that fails its checks but is not
+// at the end of the file, so cannot have been left by a writer that
+// stopped part way
+type CorruptError struct {
+	Path   string
+	Offset int64
+}
+
+func (this *CorruptError) Error() string {
+	return fmt.Sprintf("kv: %s is corrupt at byte %d", this.Path, this.Offset)
+}
+
+type record struct {
+	kind  byte
+	key   string
+	value []byte
+}
+
+// Batch is a set of changes that Write applies all together or not at all
+type Batch struct {
+	records []record
+}
+
+func (this *Batch) Put(key string, value []byte) {
+	this.records = append(this.records, record{putRecord, key, value})
+}
+
+func (this *Batch) Delete(key string) {
+	this.records = append(this.records, record{deleteRecord, key, nil})
+}
+
+type DB struct {
+	mu     sync.Mutex
+	path   string
+	file   *os.File
+	end    int64
+	values map[string][]byte
+}
+
+// Open opens the store in the named file, creating it if need be
+func Open(path string) (*DB, error) {
+	db := &DB{path: path}
+	if err := db.reopen(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+func (this *DB) Close() error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.file.Close()
+}
+
+// Get returns the value of the key and whether it has one
+func (this *DB) Get(key string) ([]byte, bool) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	value, ok := this.values[key]
+	return value, ok
+}
+
+// Keys returns the keys that start with the prefix, in order
+func (this *DB) Keys(prefix string) []string {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	keys := []string{}
+	for key := range this.values {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Refresh reads the batches that have been written since the DB was last
+// read, by this process or another. If the file has been replaced, as
+// Compact does, it is read again from the start.
+func (this *DB) Refresh() error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.refresh()
+}
+
+func (this *DB) refresh() error {
+	onDisk, err := os.Stat(this.path)
+	if os.IsNotExist(err) {
+		return this.reopen()
+	}
+	if err != nil {
+		return err
+	}
+	open, err := this.file.Stat()
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(onDisk, open) || onDisk.Size() < this.end {
+		return this.reopen()
+	}
+	return this.readFrom()
+}
+
+func (this *DB) reopen() error {
+	if this.file != nil {
+		this.file.Close()
+	}
+	f, err := os.OpenFile(this.path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	this.file = f
+	this.end = 0
+	this.values = map[string][]byte{}
+	if err := this.readFrom(); err != nil {
+		f.Close()
+		return err
+	}
+	return nil
+}
+
+// readFrom applies each complete batch after the end of the last one read.
+// It returns a *CorruptError if a record that fails its checks is not at
+// the end of the file.
+func (this *DB) readFrom() error {
+	reader := bufio.NewReader(io.NewSectionReader(this.file, this.end, 1<<62))
+	pending := []record{}
+	offset := this.end
+	for {
+		rec, size, err := readRecord(reader)
+		if err == io.EOF || err == errTorn {
+			return nil
+		}
+		if err == errBad {
+			if size == 0 {
+				size = 1
+			}
+			tail, err := this.onlyZerosFrom(offset + size)
+			if err != nil || tail {
+				return err
+			}
+			return &CorruptError{this.path, offset}
+		}
+		if err != nil {
+			return err
+		}
+		offset += size
+		if rec.kind != commitRecord {
+			pending = append(pending, rec)
+			continue
+		}
+		this.apply(pending)
+		pending = pending[:0]
+		this.end = offset
+	}
+}
+
+func (this *DB) apply(records []record) {
+	for _, rec := range records {
+		if rec.kind == putRecord {
+			this.values[rec.key] = rec.value
+		} else {
+			delete(this.values, rec.key)
+		}
+	}
+}
+
+// Write appends the batch to the file. Anything after the last complete
+// batch, left by a writer that stopped part way, is cut off first.
+func (this *DB) Write(batch *Batch) error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	if err := this.refresh(); err != nil {
+		return err
+	}
+	if err := this.file.Truncate(this.end); err != nil {
+		return err
+	}
+	var buffer bytes.Buffer
+	for _, rec := range batch.records {
+		writeRecord(&buffer, rec)
+	}
+	writeRecord(&buffer, record{kind: commitRecord})
+
+	if _, err := this.file.WriteAt(buffer.Bytes(), this.end); err != nil {
+		this.file.Truncate(this.end)
+		return err
+	}
+	if err := this.file.Sync(); err != nil {
+		this.file.Truncate(this.end)
+		return err
+	}
+	this.apply(batch.records)
+	this.end += int64(buffer.Len())
+	return nil
+}
+
+// Compact replaces the file with one that holds only the current values.
+// The new file is written beside the old one and renamed over it.
+func (this *DB) Compact() error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	if err := this.refresh(); err != nil {
+		return err
+	}
+	keys := []string{}
+	for key := range this.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var buffer bytes.Buffer
+	for _, key := range keys {
+		writeRecord(&buffer, record{putRecord, key, this.values[key]})
+	}
+	writeRecord(&buffer, record{kind: commitRecord})
+
+	dir, base := filepath.Split(this.path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, base+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tempName := f.Name()
+	_, err = f.Write(buffer.Bytes())
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempName, this.path)
+	}
+	if err != nil {
+		os.Remove(tempName)
+		return err
+	}
+	return this.reopen()
+}
+
+func writeRecord(buffer *bytes.Buffer, rec record) {
+	start := buffer.Len()
+	buffer.WriteByte(rec.kind)
+	var length [binary.MaxVarintLen64]byte
+	buffer.Write(length[:binary.PutUvarint(length[:], uint64(len(rec.key)))])
+	buffer.Write(length[:binary.PutUvarint(length[:], uint64(len(rec.value)))])
+	buffer.WriteString(rec.key)
+	buffer.Write(rec.value)
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE(buffer.Bytes()[start:]))
+	buffer.Write(sum[:])
+}
+
+// readRecord returns the next record and how many bytes it took up.
+// It returns io.EOF at the end of the file, errTorn if the file ends part
+// way through the record, and errBad if the record has an unknown kind or
+// length or fails its checksum. With errBad, the size is how many bytes the
+// record took up if that could be told, and 0 if not.
+func readRecord(reader *bufio.Reader) (record, int64, error) {
+	var raw bytes.Buffer
+	kind, err := reader.ReadByte()
+	if err != nil {
+		return record{}, 0, err
+	}
+	raw.WriteByte(kind)
+	if kind != putRecord && kind != deleteRecord && kind != commitRecord {
+		return record{}, 0, errBad
+	}
+	keyLength, err := readLength(reader, &raw)
+	if err != nil {
+		return record{}, 0, err
+	}
+	valueLength, err := readLength(reader, &raw)
+	if err != nil {
+		return record{}, 0, err
+	}
+	body := make([]byte, keyLength+valueLength+4)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return record{}, 0, torn(err)
+	}
+	raw.Write(body[0 : keyLength+valueLength])
+	if crc32.ChecksumIEEE(raw.Bytes()) != binary.BigEndian.Uint32(body[keyLength+valueLength:]) {
+		return record{}, int64(raw.Len() + 4), errBad
+	}
+	rec := record{kind, string(body[0:keyLength]), body[keyLength : keyLength+valueLength]}
+	return rec, int64(raw.Len() + 4), nil
+}
+
+func readLength(reader *bufio.Reader, raw *bytes.Buffer) (int, error) {
+	length, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return 0, torn(err)
+	}
+	if length > maxLength {
+		return 0, errBad
+	}
+	var encoded [binary.MaxVarintLen64]byte
+	raw.Write(encoded[:binary.PutUvarint(encoded[:], length)])
+	return int(length), nil
+}
+
+// onlyZerosFrom reports whether the file holds nothing but zeros from the
+// offset to its end, as it does when nothing follows the offset at all
+func (this *DB) onlyZerosFrom(offset int64) (bool, error) {
+	reader := bufio.NewReader(io.NewSectionReader(this.file, offset, 1<<62))
+	for {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if b != 0 {
+			return false, nil
+		}
+	}
+}
+
+// torn turns running out of file part way through a record into errTorn
+func torn(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errTorn
+	}
+	return fmt.Errorf("kv: %w", err)
+}
@@ -0,0 +1,172 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package kv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func open(t *testing.T, path string) *DB {
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func write(t *testing.T, db *DB, changes ...string) {
+	batch := Batch{}
+	for _, change := range changes {
+		if key, value, ok := strings.Cut(change, "="); ok {
+			batch.Put(key, []byte(value))
+		} else {
+			batch.Delete(change)
+		}
+	}
+	if err := db.Write(&batch); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// contents lists every key and value in order
+func contents(db *DB) string {
+	output := []string{}
+	for _, key := range db.Keys("") {
+		value, _ := db.Get(key)
+		output = append(output, key+"="+string(value))
+	}
+	return strings.Join(output, " ")
+}
+
+func TestWriteAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acts.db")
+	db := open(t, path)
+	write(t, db, "a=1", "b=2", "c=3")
+	write(t, db, "b", "a=one")
+	if got, want := contents(db), "a=one c=3"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := strings.Join(db.Keys("c"), " "), "c"; got != want {
+		t.Errorf("keys got %q, want %q", got, want)
+	}
+	if got, want := contents(open(t, path)), "a=one c=3"; got != want {
+		t.Errorf("reopened got %q, want %q", got, want)
+	}
+}
+
+func TestRefreshSeesOtherWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acts.db")
+	reader := open(t, path)
+	writer := open(t, path)
+	write(t, writer, "a=1")
+	if err := reader.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if got := contents(reader); got != "a=1" {
+		t.Errorf("got %q after a write, want a=1", got)
+	}
+	if err := writer.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	write(t, writer, "b=2")
+	if err := reader.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if got := contents(reader); got != "a=1 b=2" {
+		t.Errorf("got %q after a compaction, want a=1 b=2", got)
+	}
+}
+
+func TestTornBatchIsIgnored(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acts.db")
+	db := open(t, path)
+	write(t, db, "a=1")
+	write(t, db, "b=2")
+	info, _ := os.Stat(path)
+	for cut := int64(1); cut < 10; cut++ {
+		os.Truncate(path, info.Size()-cut)
+		if got := contents(open(t, path)); got != "a=1" {
+			t.Errorf("cut %d bytes: got %q, want the last batch left out", cut, got)
+		}
+	}
+
+	// the next write replaces what was left of the torn batch
+	again := open(t, path)
+	write(t, again, "c=3")
+	if got := contents(open(t, path)); got != "a=1 c=3" {
+		t.Errorf("got %q, want a=1 c=3", got)
+	}
+}
+
+func TestCorruptLastRecordIsIgnored(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acts.db")
+	db := open(t, path)
+	write(t, db, "a=1")
+	write(t, db, "b=2")
+	data, _ := os.ReadFile(path)
+	data[len(data)-1] ^= 0xff
+	os.WriteFile(path, data, 0600)
+	if got := contents(open(t, path)); got != "a=1" {
+		t.Errorf("got %q, want the corrupt batch left out", got)
+	}
+
+	// as is a batch followed only by the zeros a crash can leave
+	os.WriteFile(path, append(data, make([]byte, 64)...), 0600)
+	if got := contents(open(t, path)); got != "a=1" {
+		t.Errorf("got %q with zeros at the end, want the corrupt batch left out", got)
+	}
+}
+
+func TestCorruptMiddleBatchIsAnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acts.db")
+	db := open(t, path)
+	write(t, db, "a=1")
+	first, _ := os.Stat(path)
+	other := open(t, path)
+	write(t, db, "b=2")
+	write(t, db, "c=3")
+	data, _ := os.ReadFile(path)
+	data[first.Size()+3] ^= 0xff
+	os.WriteFile(path, data, 0600)
+
+	var corrupt *CorruptError
+	if _, err := Open(path); !errors.As(err, &corrupt) || corrupt.Offset != first.Size() {
+		t.Errorf("open got %v, want it corrupt at byte %d", err, first.Size())
+	}
+	batch := Batch{}
+	batch.Put("d", []byte("4"))
+	if err := other.Write(&batch); !errors.As(err, &corrupt) {
+		t.Errorf("write got %v, want it corrupt", err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(data) {
+		t.Errorf("the file was changed from %d bytes to %d, want the later batches kept", len(data), len(after))
+	}
+}
+
+func TestCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "acts.db")
+	db := open(t, path)
+	for i := 0; i < 20; i++ {
+		write(t, db, "a="+strings.Repeat("x", i))
+	}
+	before, _ := os.Stat(path)
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Errorf("compacted from %d to %d bytes", before.Size(), after.Size())
+	}
+	if got, want := contents(open(t, path)), "a="+strings.Repeat("x", 19); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
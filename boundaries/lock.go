@@ -0,0 +1,36 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import "os"
+
+// The lock is taken on a file beside the logfile rather than on the logfile
+// itself because Compact replaces the logfile with a new one.
+func (this Logfile) lock(exclusive bool) (func(), error) {
+	return lockFile(this.Filename+".lock", exclusive)
+}
+
+// lockFile waits until no other process is writing, and if exclusive is
+// true until no other process is reading either, by locking the named file.
+// It returns the function that releases the lock.
+//
+// A reader that may not create the lock file reads without it, so that
+// storage in a read-only place can still be read.
+func lockFile(lockFilename string, exclusive bool) (func(), error) {
+	f, err := os.OpenFile(lockFilename, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		if !exclusive && os.IsPermission(err) {
+			return func() {}, nil
+		}
+		return nil, &IOError{"lock", lockFilename, err}
+	}
+	if err := flock(f, exclusive); err != nil {
+		f.Close()
+		return nil, &IOError{"lock", lockFilename, err}
+	}
+	return func() { f.Close() }, nil
+}
@@ -0,0 +1,19 @@
+//go:build !unix
+
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import "os"
+
+// flock does nothing where there is no flock(2), such as on Windows.
+// Nothing then stops two processes writing one datafile at once, and the
+// check in Store.commit cannot see a change the other is still making, so
+// the README and the man page say to run only one acts at a time there.
+func flock(f *os.File, exclusive bool) error {
+	return nil
+}
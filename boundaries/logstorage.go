@@ -7,186 +7,328 @@ See LICENSE.txt for terms of usage.
 package boundaries
 
 import (
-	"crypto/sha1"
+	"bufio"
+	"bytes"
+	"crypto/rand"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
-	"bufio"
 	"github.com/Fepelus/ActivityStream/entities"
 )
 
+// Logfile is the journal kept as a text file, one LogLine to each line.
 type Logfile struct {
 	Filename string
 }
 
+// FileStore keeps the activities in the named logfile
+func FileStore(filename string) *Store {
+	return &Store{journal: Logfile{Filename: filename}}
+}
+
 type LogLine struct {
 	Id       string
 	Now      time.Time
 	Command  string
 	Activity entities.OneActivity
+	// Size is the number of lines that follow in the same transaction if
+	// this line is a transaction header, otherwise 0
+	Size int
 }
 
 const (
-	idxLength = 3
-	Tformat   = "2006-01-02T15:04:05"
-	Bformat   = "2006-01-02 15:04"
+	// Tformat is the stamp of lines written before the stamp carried its
+	// offset; those are read in the local zone
+	Tformat = "2006-01-02T15:04:05"
+	Bformat = "2006-01-02 15:04"
 )
 
+// Logfiles written before zones were recorded in them hold Melbourne times
+var legacyZone = loadLegacyZone()
+
+func loadLegacyZone() *time.Location {
+	loc, err := entities.LoadZone("Australia/Melbourne")
+	if err != nil {
+		return time.FixedZone("+10:00", 10*60*60)
+	}
+	return loc
+}
+
+// removes is true for a line that takes its activity out of the stream,
+// whether it was done, cancelled or deleted
+func (this LogLine) removes() bool {
+	switch this.Command {
+	case entities.DoneCommand, entities.CancelCommand, entities.DeleteCommand:
+		return true
+	}
+	return false
+}
+
 func (this LogLine) String() string {
-	return fmt.Sprintf("[%s] %s\n", this.Id[0:idxLength], this.Activity)
+	return fmt.Sprintf("[%s] %s\n", this.Id, this.Activity)
 }
 func (this LogLine) LogString() string {
-	now := this.Now.Format(Tformat)
-	return fmt.Sprintf("[%s] %s: (%s) %s\n", now, this.Command, this.Id, this.Activity)
+	now := this.Now.Format(time.RFC3339)
+	if this.Size > 0 {
+		return fmt.Sprintf("[%s] %s: %d\n", now, this.Command, this.Size)
+	}
+	id := this.Id
+	if this.Activity.Predecessor != "" {
+		id += " from " + this.Activity.Predecessor
+	}
+	return fmt.Sprintf("[%s] %s: (%s) %s\n", now, this.Command, id, this.Activity.FullString())
+}
+
+/* example input: "[2014-07-13T19:24:09+10:00] ADD: (414a4ec94c5b4c0f859b5f7cf721fceba05b4d84) 2014-05-05 05:07  Bam!" */
+// An activity delayed or rescheduled from another also names that one, as
+// in "ADD: (9b1c0d... from 414a4e...)".
+var logLinePattern = regexp.MustCompile("\\[(\\d{4}-\\d{2}-\\d{2}T\\d{2}:\\d{2}:\\d{2}(?:Z|[+-]\\d{2}:\\d{2})?)\\] ([^:]+): \\(([0-9a-f]+)(?: from ([0-9a-f]+))?\\) (\\d{4}-\\d{2}-\\d{2} \\d{2}:\\d{2} .*)")
+
+// logCommands are the commands a line of an activity may have: ADD for a
+// new activity, EDIT for one changed under the same ID, and DONE, CANCEL
+// or DELETE for one taken out
+var logCommands = map[string]bool{
+	entities.AddCommand:    true,
+	entities.EditCommand:   true,
+	entities.DoneCommand:   true,
+	entities.CancelCommand: true,
+	entities.DeleteCommand: true,
 }
 
-/* example input: "[2014-07-13T19:24:09] ADD: (414a4ec94c5b4c0f859b5f7cf721fceba05b4d84) 2014-05-05 05:07  Bam!" */
+/* example input: "[2014-07-13T19:24:09+10:00] DELAY: 2" */
+var headerPattern = regexp.MustCompile("^\\[(\\d{4}-\\d{2}-\\d{2}T\\d{2}:\\d{2}:\\d{2}(?:Z|[+-]\\d{2}:\\d{2})?)\\] ([A-Z]+): ([1-9][0-9]*)$")
+
 // ParseLogLine will take a single line of the logfile format
 // and return the LogLine struct that represents it.
-func ParseLogLine(input string) LogLine {
-	regstring := "\\[(\\d{4}-\\d{2}-\\d{2}T\\d{2}:\\d{2}:\\d{2})\\] ([^:]+): \\(([0-9a-f]+)\\) (\\d{4}-\\d{2}-\\d{2} \\d{2}:\\d{2} .*)"
-	r, _ := regexp.Compile(regstring)
-	match := r.FindStringSubmatch(input)
+// It returns a *ParseError if the line is not in that format.
+func ParseLogLine(input string) (LogLine, error) {
+	if header := headerPattern.FindStringSubmatch(input); header != nil {
+		return parseHeader(input, header)
+	}
+	match := logLinePattern.FindStringSubmatch(input)
 	/*
-	   [1]: 2014-07-13T19:24:09
+	   [1]: 2014-07-13T19:24:09+10:00
 	   [2]: ADD
 	   [3]: 414a4ec94c5b4c0f859b5f7cf721fceba05b4d84
-	   [4]: 2014-05-05 05:07  Bam!
+	   [4]: the ID it came from, or ""
+	   [5]: 2014-05-05 05:07  Bam!
 	*/
 	if match == nil {
-		fmt.Println("COULD NOT MATCH INPUT: ", input)
-		return LogLine{}
+		return LogLine{}, &ParseError{Input: input, Err: fmt.Errorf("not in the format '[NOW] COMMAND: (ID) YYYY-MM-DD HH:MM text'")}
+	}
+	nowstamp, err := parseNow(match[1])
+	if err != nil {
+		return LogLine{}, &ParseError{Input: input, Err: err}
+	}
+	if !logCommands[match[2]] {
+		return LogLine{}, &ParseError{Input: input, Err: fmt.Errorf("the command '%s' is not ADD, EDIT, DONE, CANCEL or DELETE", match[2])}
 	}
-	nowstamp, _ := time.Parse(match[1], Tformat)
-	activity, _ := entities.ParseOneActivity(match[4])
-	activity.Id = match[3][0:idxLength]
-	return LogLine{match[3], nowstamp, match[2], activity}
+	activity, err := entities.ParseOneActivityIn(match[5], legacyZone)
+	if err != nil {
+		return LogLine{}, &ParseError{Input: input, Err: err}
+	}
+	activity.Id = match[3]
+	activity.Predecessor = match[4]
+	return LogLine{Id: match[3], Now: nowstamp, Command: match[2], Activity: activity}, nil
 }
 
-/* Probably should return the error rather than eat it */
-func (this Logfile) AddNew(activity entities.OneActivity) string {
-
-	thisLine := LogLine{
-		sha(activity.String()),
-		time.Now(),
-		"ADD",
-		activity,
+func parseHeader(input string, header []string) (LogLine, error) {
+	/*
+	   [1]: 2014-07-13T19:24:09+10:00
+	   [2]: DELAY
+	   [3]: 2
+	*/
+	nowstamp, err := parseNow(header[1])
+	if err != nil {
+		return LogLine{}, &ParseError{Input: input, Err: err}
 	}
+	size, err := strconv.Atoi(header[3])
+	if err != nil {
+		return LogLine{}, &ParseError{Input: input, Err: err}
+	}
+	return LogLine{Now: nowstamp, Command: header[2], Size: size}, nil
+}
 
-	_ = this.appendThisLine(thisLine)
-
-	return thisLine.Id[0:idxLength]
+// parseNow reads the stamp of when a line was written. It carries its
+// offset, unless the line was written before it did.
+func parseNow(stamp string) (time.Time, error) {
+	if len(stamp) > len(Tformat) {
+		return time.Parse(time.RFC3339, stamp)
+	}
+	return time.ParseInLocation(Tformat, stamp, time.Local)
 }
 
-func (this Logfile) appendThisLine(logline LogLine) error {
-	f, err := os.OpenFile(this.Filename, os.O_APPEND|os.O_WRONLY, 0600)
+// addLine gives the activity a new ID, made up once and kept in the
+// journal from then on, so that activities alike are still told apart
+func addLine(activity entities.OneActivity, now time.Time) (LogLine, error) {
+	id, err := newId()
 	if err != nil {
-		if os.IsNotExist(err) {
-			f, err = os.Create(this.Filename)
-			if err == nil {
-				f.Close()
-				return this.appendThisLine(logline)
-			}
-		}
-		return err
+		return LogLine{}, err
 	}
-
-	defer f.Close()
-	_, err = f.WriteString(logline.LogString())
-	return err
+	activity.Id = id
+	return LogLine{Id: id, Now: now, Command: "ADD", Activity: activity}, nil
 }
 
-func sha(input string) string {
-	hasher := sha1.New()
-	hasher.Write([]byte(input))
-	return fmt.Sprintf("%x", hasher.Sum(nil))
+// removalLine takes the activity out with the command DONE, CANCEL or
+// DELETE. It refers to the activity by the ID it was added with, never by
+// what it says, so it removes that activity and no other.
+func removalLine(command string, activity entities.OneActivity, now time.Time) (LogLine, error) {
+	if activity.Id == "" {
+		return LogLine{}, fmt.Errorf("Cannot remove '%s' as it has no ID", activity.FullString())
+	}
+	return LogLine{Id: activity.Id, Now: now, Command: command, Activity: activity}, nil
 }
 
-func (this Logfile) GetAll() entities.Activities {
-	foundActivities := map[string]entities.OneActivity{}
-	f, _ := os.Open(this.Filename)
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		thisline := ParseLogLine(scanner.Text())
-		if thisline.Command == "ADD" {
-			foundActivities[thisline.Id] = thisline.Activity
-		}
-		if thisline.Command == "DELETE" {
-			delete(foundActivities, thisline.Id)
-		}
+// editLine records the activity as it now is under the ID it already has
+func editLine(activity entities.OneActivity, now time.Time) (LogLine, error) {
+	if activity.Id == "" {
+		return LogLine{}, fmt.Errorf("Cannot edit '%s' as it has no ID", activity.FullString())
 	}
-	output := entities.Activities{}
-	for _, v := range foundActivities {
-		output = append(output, v)
+	return LogLine{Id: activity.Id, Now: now, Command: "EDIT", Activity: activity}, nil
+}
+
+// newId is 40 random hexadecimal digits, the same shape as the IDs of
+// older logfiles, which were the SHA-1 of the activity
+func newId() (string, error) {
+	random := make([]byte, 20)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("Could not make an ID: %s", err)
 	}
-	return output
+	return fmt.Sprintf("%x", random), nil
 }
 
-func (this Logfile) FindActivity(id string) entities.Activities {
-	loglines := []LogLine{}
-	output := entities.Activities{}
-	f, _ := os.Open(this.Filename)
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		thisline := ParseLogLine(scanner.Text())
-		if thisline.Id[0:len(id)] == id {
-			loglines = append(loglines, thisline)
-		}
+// appendTransaction appends the transaction to the logfile after the last
+// transaction that was completely written, first cutting off anything after
+// that which a writer that stopped part way left behind, so that the new
+// lines are never read as the rest of it. If the lines cannot all be
+// written then the logfile is cut back to that transaction again.
+// The caller must hold the exclusive lock.
+func (this Logfile) appendTransaction(t transaction) error {
+	end, terminated, err := this.completeEnd()
+	if err != nil {
+		return err
 	}
-	for _, el := range removeDeletedActivities(loglines) {
-		output = append(output, el.Activity)
+	var buffer bytes.Buffer
+	if !terminated {
+		buffer.WriteString("\n")
+	}
+	for _, line := range t.LogStrings() {
+		buffer.WriteString(line + "\n")
 	}
-	return output
-}
 
-func removeDeletedActivities(input []LogLine) []LogLine {
-	output := []LogLine{}
-	deletedList := []string{}
-	for _, firstlogline := range input {
-		if firstlogline.Command == "DELETE" {
-			deletedList = append(deletedList, firstlogline.Id)
-		}
+	f, err := os.OpenFile(this.Filename, os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return &IOError{"append to", this.Filename, err}
 	}
-	for _, secondlogline := range input {
-		if !stringInSlice(secondlogline.Id, deletedList) {
-			output = append(output, secondlogline)
-		}
+	err = f.Truncate(end)
+	if err == nil {
+		_, err = f.WriteAt(buffer.Bytes(), end)
+	}
+	if err != nil {
+		f.Truncate(end)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
 	}
-	return output
+	if err != nil {
+		return &IOError{"append to", this.Filename, err}
+	}
+	return nil
 }
 
-func stringInSlice(a string, list []string) bool {
-	for _, b := range list {
-		if b == a {
-			return true
+// readLines calls the given function with each line of the logfile in turn,
+// the offset just after it, and whether it ends in a newline, which only
+// the last line may not. A logfile that does not exist yet has no lines.
+// The caller must hold a lock.
+func (this Logfile) readLines(each func(line string, end int64, terminated bool) error) error {
+	f, err := os.Open(this.Filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return &IOError{"open", this.Filename, err}
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	end := int64(0)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return &IOError{"read", this.Filename, err}
+		}
+		if line == "" {
+			return nil
+		}
+		end += int64(len(line))
+		terminated := strings.HasSuffix(line, "\n")
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if err := each(line, end, terminated); err != nil {
+			return err
 		}
 	}
-	return false
 }
 
-func (this Logfile) Delete(activity entities.OneActivity) error {
-	thisLine := LogLine{
-		sha(activity.String()),
-		time.Now(),
-		"DELETE",
-		activity,
-	}
+// readTransactions calls the given function with each transaction in the
+// logfile in turn. A transaction whose lines stop before its header says
+// they should was not completely written, and is left out, as is a last
+// line that has no newline and cannot be read.
+// The caller must hold a lock.
+func (this Logfile) readTransactions(each func(transaction) error) error {
+	return this.scan(func(t transaction, end int64, terminated bool) error {
+		return each(t)
+	})
+}
 
-	return this.appendThisLine(thisLine)
+// completeEnd is the offset just after the last transaction that was
+// completely written, and whether its last line ends in a newline.
+// The caller must hold a lock.
+func (this Logfile) completeEnd() (int64, bool, error) {
+	end, terminated := int64(0), true
+	err := this.scan(func(t transaction, lineEnd int64, lineTerminated bool) error {
+		end, terminated = lineEnd, lineTerminated
+		return nil
+	})
+	return end, terminated, err
 }
 
-func (this Logfile) Grep(id string) []string {
-	loglines := []string{}
-	f, _ := os.Open(this.Filename)
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		thisline := scanner.Text()
-		if strings.Contains(thisline, id) {
-			loglines = append(loglines, thisline)
+// scan is readTransactions, also passing the offset just after each
+// transaction and whether its last line ends in a newline
+func (this Logfile) scan(each func(t transaction, end int64, terminated bool) error) error {
+	end, terminated := int64(0), true
+	reader := transactionReader{each: func(t transaction) error {
+		return each(t, end, terminated)
+	}}
+	lineNumber := 0
+	return this.readLines(func(line string, lineEnd int64, lineTerminated bool) error {
+		lineNumber++
+		thisline, err := parseLine(line, this.Filename, lineNumber)
+		if err != nil && !lineTerminated {
+			return nil
+		}
+		if err != nil {
+			return err
 		}
+		end, terminated = lineEnd, lineTerminated
+		return reader.add(thisline)
+	})
+}
+
+func (this Logfile) close() error { return nil }
+
+// parseLine is ParseLogLine with errors that say where the line is
+func parseLine(line, filename string, lineNumber int) (LogLine, error) {
+	thisline, err := ParseLogLine(line)
+	if err != nil {
+		parseErr := err.(*ParseError)
+		parseErr.Filename = filename
+		parseErr.Line = lineNumber
+		return LogLine{}, parseErr
 	}
-	return loglines
+	return thisline, nil
 }
@@ -0,0 +1,102 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseLogLine(t *testing.T) {
+	melbourne := time.Date(2014, 5, 5, 5, 7, 0, 0, legacyZone)
+	tests := []struct {
+		input      string
+		command    string
+		id         string
+		from       string
+		size       int
+		timestamp  time.Time
+		commandTag string
+		body       string
+		wantErr    bool
+	}{
+		{input: "[2014-07-13T19:24:09] ADD: (414a4ec94c5b4c0f859b5f7cf721fceba05b4d84) 2014-05-05 05:07  Bam!",
+			command: "ADD", id: "414a4ec94c5b4c0f859b5f7cf721fceba05b4d84", timestamp: melbourne, body: " Bam!"},
+		{input: "[2014-07-13T19:24:09] DELETE: (414a4e) 2014-05-05 05:07 UTC Bam!",
+			command: "DELETE", id: "414a4e", timestamp: time.Date(2014, 5, 5, 5, 7, 0, 0, time.UTC), body: "Bam!"},
+		{input: "[2014-07-13T19:24:09] ADD: (414a4e) 2014-05-05 05:07 Australia/Melbourne @rtask:every-n-days:1 Bam!",
+			command: "ADD", id: "414a4e", timestamp: melbourne, commandTag: "every-n-days:1", body: "Bam!"},
+		{input: "[2014-07-13T19:24:09] EDIT: (414a4e) 2014-05-05 05:07 UTC @rtask:every-n-days:2 Bam!",
+			command: "EDIT", id: "414a4e", timestamp: time.Date(2014, 5, 5, 5, 7, 0, 0, time.UTC), commandTag: "every-n-days:2", body: "Bam!"},
+		{input: "[2014-07-13T19:24:09] DONE: (414a4e) 2014-05-05 05:07 UTC Bam!",
+			command: "DONE", id: "414a4e", timestamp: time.Date(2014, 5, 5, 5, 7, 0, 0, time.UTC), body: "Bam!"},
+		{input: "[2014-07-13T19:24:09] CANCEL: (414a4e) 2014-05-05 05:07 UTC Bam!",
+			command: "CANCEL", id: "414a4e", timestamp: time.Date(2014, 5, 5, 5, 7, 0, 0, time.UTC), body: "Bam!"},
+		{input: "[2014-07-13T19:24:09] ADD: (9b1c0d from 414a4e) 2014-05-06 05:07 UTC Bam!",
+			command: "ADD", id: "9b1c0d", from: "414a4e", timestamp: time.Date(2014, 5, 6, 5, 7, 0, 0, time.UTC), body: "Bam!"},
+		{input: "[2014-07-13T19:24:09] DELAY: 2", command: "DELAY", size: 2},
+		{input: "[2014-07-13T19:24:09+10:00] ADD: (414a4e) 2014-05-05 05:07 UTC Bam!",
+			command: "ADD", id: "414a4e", timestamp: time.Date(2014, 5, 5, 5, 7, 0, 0, time.UTC), body: "Bam!"},
+		{input: "[2014-07-13T09:24:09Z] DELAY: 2", command: "DELAY", size: 2},
+		{input: "[2014-07-13T19:24:09+25:00] DELAY: 2", wantErr: true},
+		{input: "[2014-07-13T19:24:09] CHANGE: (414a4e) 2014-05-05 05:07 UTC Bam!", wantErr: true},
+		{input: "[2014-07-13T19:24:09] DELAY: 0", wantErr: true},
+		{input: "", wantErr: true},
+		{input: "2014-05-05 05:07 Bam!", wantErr: true},
+		{input: "[2014-07-13T19:24:09] ADD: (XYZ) 2014-05-05 05:07 Bam!", wantErr: true},
+		{input: "[2014-07-13T19:24:09] ADD: (9b1c0d from ) 2014-05-05 05:07 Bam!", wantErr: true},
+		{input: "[2014-07-13T19:24:09] ADD: (414a4e) 2014-13-05 05:07 Bam!", wantErr: true},
+		{input: "[2014-07-13T25:24:09] ADD: (414a4e) 2014-05-05 05:07 Bam!", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseLogLine(test.input)
+		if test.wantErr {
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) || parseErr.Input != test.input {
+				t.Errorf("%q: got %+v, %v, want a ParseError", test.input, got, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+			continue
+		}
+		if got.Command != test.command || got.Id != test.id || got.Size != test.size ||
+			got.Activity.Id != test.id || got.Activity.Predecessor != test.from || !got.Activity.Timestamp.Equal(test.timestamp) ||
+			got.Activity.CommandTag != test.commandTag || got.Activity.Body != test.body {
+			t.Errorf("%q: got %+v", test.input, got)
+		}
+		if got.Size == 0 {
+			if again, _ := ParseLogLine(got.LogString()[0 : len(got.LogString())-1]); again.Activity.FullString() != got.Activity.FullString() || again.Activity.Predecessor != test.from {
+				t.Errorf("%q: LogString %q reads back as %+v", test.input, got.LogString(), again)
+			}
+		}
+	}
+}
+
+func TestLogStampKeepsItsOffset(t *testing.T) {
+	now := time.Date(2014, 7, 13, 19, 24, 9, 0, time.FixedZone("", 10*60*60))
+	for _, line := range []LogLine{
+		{Id: "414a4e", Now: now, Command: "ADD", Activity: newActivity("2014-05-05 05:07", "Bam!")},
+		{Now: now, Command: "DELAY", Size: 2},
+	} {
+		written := line.LogString()
+		if written[:27] != "[2014-07-13T19:24:09+10:00]" {
+			t.Errorf("got %q, want it stamped with its offset", written)
+		}
+		got, err := ParseLogLine(written[:len(written)-1])
+		if err != nil || !got.Now.Equal(now) {
+			t.Errorf("%q: got %v, %v, want %v", written, got.Now, err, now)
+		}
+	}
+
+	old, err := ParseLogLine("[2014-07-13T19:24:09] DELAY: 2")
+	if want := time.Date(2014, 7, 13, 19, 24, 9, 0, time.Local); err != nil || !old.Now.Equal(want) {
+		t.Errorf("got %v, %v, want a stamp without an offset read as local time %v", old.Now, err, want)
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import (
+	"sync"
+	"time"
+)
+
+// memory is the journal kept in memory, for tests and for programs that
+// have no need to keep their activities once they stop.
+type memory struct {
+	mu           sync.RWMutex
+	transactions []transaction
+	archived     []string
+}
+
+// MemoryStore keeps the activities in memory
+func MemoryStore() *Store {
+	return &Store{journal: &memory{}}
+}
+
+func (this *memory) lock(exclusive bool) (func(), error) {
+	if exclusive {
+		this.mu.Lock()
+		return this.mu.Unlock, nil
+	}
+	this.mu.RLock()
+	return this.mu.RUnlock, nil
+}
+
+func (this *memory) readTransactions(each func(transaction) error) error {
+	for _, t := range this.transactions {
+		if err := each(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *memory) appendTransaction(t transaction) error {
+	this.transactions = append(this.transactions, t)
+	return nil
+}
+
+func (this *memory) replaceWith(kept []transaction) error {
+	this.transactions = append([]transaction{}, kept...)
+	return nil
+}
+
+// archive keeps the lines in memory with the rest
+func (this *memory) archive(lines []string, now time.Time) (string, error) {
+	this.archived = append(this.archived, lines...)
+	return "memory", nil
+}
+
+func (this *memory) close() error { return nil }
@@ -0,0 +1,78 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import (
+	"strings"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+// Migrate gives fresh IDs to the activities of a journal written when an
+// ID was the SHA-1 of the activity, so that an activity added again while
+// an identical one was still there is an activity of its own rather than
+// the same one twice. It returns how many activities were given new IDs.
+//
+// Each later DONE, CANCEL or DELETE of the shared ID also removes the
+// copies, so whatever was done or deleted stays so; a single line that
+// grows is named for what Undo called it. An ID that was never shared is
+// kept as it is, as it is already stored with its activity.
+func (this *Store) Migrate() (int, error) {
+	unlock, err := this.journal.lock(true)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	transactions := []transaction{}
+	live := map[string]bool{}
+	copies := map[string][]string{}
+	renamed := 0
+	err = this.journal.readTransactions(func(t transaction) error {
+		lines := []LogLine{}
+		for _, thisline := range t.Lines {
+			switch {
+			case thisline.Command == "ADD":
+				if live[thisline.Id] {
+					id, err := newId()
+					if err != nil {
+						return err
+					}
+					copies[thisline.Id] = append(copies[thisline.Id], id)
+					thisline.Id = id
+					thisline.Activity.Id = id
+					renamed++
+				}
+				live[thisline.Id] = true
+			case thisline.removes():
+				for _, id := range copies[thisline.Id] {
+					twin := thisline
+					twin.Id = id
+					twin.Activity.Id = id
+					lines = append(lines, twin)
+					delete(live, id)
+				}
+				delete(copies, thisline.Id)
+				delete(live, thisline.Id)
+			}
+			lines = append(lines, thisline)
+		}
+		if t.Name == "" && len(t.Lines) == 1 && len(lines) > 1 {
+			t.Name = strings.ToUpper(t.operation(map[string]entities.OneActivity{}).Name)
+		}
+		t.Lines = lines
+		transactions = append(transactions, t)
+		return nil
+	})
+	if err != nil || renamed == 0 {
+		return 0, err
+	}
+	if err := this.journal.replaceWith(transactions); err != nil {
+		return 0, err
+	}
+	return renamed, nil
+}
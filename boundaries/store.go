@@ -0,0 +1,302 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+// Store keeps the activities as a journal of the changes made to them.
+// It has every method the use cases ask of storage, whichever backend
+// holds the journal. Each change is stamped with the time the Clock says,
+// or the computer's time if there is no Clock.
+type Store struct {
+	journal journal
+	Clock   entities.Clock
+}
+
+// Open opens the store at the location, which says which backend to use:
+//
+//	file:///home/me/logfile.txt   the text logfile (the default for a plain path)
+//	kv:///home/me/acts.db         the embedded key/value database
+//	mem://                        memory, gone when the program stops
+func Open(location string) (*Store, error) {
+	if !strings.Contains(location, "://") {
+		return FileStore(location), nil
+	}
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("Store location '%s' is not a URL: %s", location, err)
+	}
+	path := parsed.Host + parsed.Path
+	switch parsed.Scheme {
+	case "file":
+		return FileStore(path), nil
+	case "kv":
+		return DatabaseStore(path)
+	case "mem":
+		return MemoryStore(), nil
+	}
+	return nil, fmt.Errorf("Store '%s' not found. Legal stores are 'file://','kv://','mem://'", parsed.Scheme)
+}
+
+// Close lets go of anything the backend holds open
+func (this *Store) Close() error {
+	return this.journal.close()
+}
+
+func (this *Store) now() time.Time {
+	if this.Clock == nil {
+		return time.Now()
+	}
+	return this.Clock.Now()
+}
+
+// commit writes the lines as a single transaction while holding the
+// exclusive lock. The activities that the lines take out or edit must still
+// be in the stream once the lock is held: if another process took one out
+// after it was read, nothing is written and a *ConflictError is returned.
+func (this *Store) commit(operation string, lines ...LogLine) error {
+	unlock, err := this.journal.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := this.checkLive(lines); err != nil {
+		return err
+	}
+	return this.journal.appendTransaction(newTransaction(operation, lines...))
+}
+
+// checkLive returns a *ConflictError for the first of the lines, other
+// than an ADD, whose activity is not in the stream.
+// The caller must hold a lock.
+func (this *Store) checkLive(lines []LogLine) error {
+	live := map[string]bool{}
+	for _, thisline := range lines {
+		if thisline.Command != "ADD" {
+			live[thisline.Id] = false
+		}
+	}
+	if len(live) == 0 {
+		return nil
+	}
+	err := this.journal.readTransactions(func(t transaction) error {
+		for _, thisline := range t.Lines {
+			if _, wanted := live[thisline.Id]; !wanted {
+				continue
+			}
+			if thisline.Command == "ADD" {
+				live[thisline.Id] = true
+			}
+			if thisline.removes() {
+				live[thisline.Id] = false
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, thisline := range lines {
+		if thisline.Command != "ADD" && !live[thisline.Id] {
+			return &ConflictError{thisline.Id}
+		}
+	}
+	return nil
+}
+
+// eachLogLine calls the given function with each line of the journal in
+// turn, leaving out transaction headers and any transaction that was not
+// completely written. It holds a shared lock while it reads.
+func (this *Store) eachLogLine(each func(LogLine)) error {
+	unlock, err := this.journal.lock(false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return this.journal.readTransactions(func(t transaction) error {
+		for _, thisline := range t.Lines {
+			each(thisline)
+		}
+		return nil
+	})
+}
+
+func (this *Store) AddNew(activity entities.OneActivity) (string, error) {
+	thisLine, err := addLine(activity, this.now())
+	if err != nil {
+		return "", err
+	}
+	if err := this.commit("", thisLine); err != nil {
+		return "", err
+	}
+	return thisLine.Id, nil
+}
+
+func (this *Store) GetAll() (entities.Activities, error) {
+	foundActivities := map[string]entities.OneActivity{}
+	err := this.eachLogLine(func(thisline LogLine) {
+		switch thisline.Command {
+		case "ADD":
+			foundActivities[thisline.Id] = thisline.Activity
+		case "EDIT":
+			if _, ok := foundActivities[thisline.Id]; ok {
+				foundActivities[thisline.Id] = thisline.Activity
+			}
+		}
+		if thisline.removes() {
+			delete(foundActivities, thisline.Id)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	output := entities.Activities{}
+	for _, v := range foundActivities {
+		output = append(output, v)
+	}
+	return output, nil
+}
+
+func (this *Store) FindActivity(id string) (entities.Activities, error) {
+	loglines := []LogLine{}
+	output := entities.Activities{}
+	err := this.eachLogLine(func(thisline LogLine) {
+		if strings.HasPrefix(thisline.Id, id) {
+			loglines = append(loglines, thisline)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, el := range removeDeletedActivities(loglines) {
+		output = append(output, el.Activity)
+	}
+	return output, nil
+}
+
+// removeDeletedActivities keeps the latest ADD or EDIT line of each
+// activity that has not since been removed, in the order they were first
+// added. An activity put back by Undo is added again under the ID it was
+// deleted with.
+func removeDeletedActivities(input []LogLine) []LogLine {
+	latest := map[string]LogLine{}
+	order := []string{}
+	for _, thisline := range input {
+		_, live := latest[thisline.Id]
+		switch thisline.Command {
+		case "ADD":
+			if !live {
+				order = append(order, thisline.Id)
+			}
+			latest[thisline.Id] = thisline
+		case "EDIT":
+			if live {
+				latest[thisline.Id] = thisline
+			}
+		}
+		if thisline.removes() {
+			delete(latest, thisline.Id)
+		}
+	}
+	output := []LogLine{}
+	for _, id := range order {
+		if thisline, live := latest[id]; live {
+			output = append(output, thisline)
+			delete(latest, id)
+		}
+	}
+	return output
+}
+
+// Delete records that the activity is gone, by the ID it was added with,
+// without saying that it was done or cancelled
+func (this *Store) Delete(activity entities.OneActivity) error {
+	return this.remove(entities.DeleteCommand, activity)
+}
+
+// Done records that the activity was done
+func (this *Store) Done(activity entities.OneActivity) error {
+	return this.remove(entities.DoneCommand, activity)
+}
+
+// Cancel records that the activity will not be done
+func (this *Store) Cancel(activity entities.OneActivity) error {
+	return this.remove(entities.CancelCommand, activity)
+}
+
+func (this *Store) remove(command string, activity entities.OneActivity) error {
+	thisLine, err := removalLine(command, activity, this.now())
+	if err != nil {
+		return err
+	}
+	return this.commit("", thisLine)
+}
+
+// Replace deletes the old activity and adds the new one in a single
+// transaction named for the operation, such as "delay", so that no reader
+// sees one without the other. It returns the ID of the new activity.
+func (this *Store) Replace(old, replacement entities.OneActivity, operation string) (string, error) {
+	return this.replace(entities.DeleteCommand, old, replacement, operation)
+}
+
+// Repeat records that the activity was done and adds the next occurrence
+// of it in a single "repeat" transaction. It returns the ID of the next
+// occurrence.
+func (this *Store) Repeat(done, next entities.OneActivity) (string, error) {
+	return this.replace(entities.DoneCommand, done, next, "repeat")
+}
+
+func (this *Store) replace(command string, old, replacement entities.OneActivity, operation string) (string, error) {
+	now := this.now()
+	oldLine, err := removalLine(command, old, now)
+	if err != nil {
+		return "", err
+	}
+	newLine, err := addLine(replacement, now)
+	if err != nil {
+		return "", err
+	}
+	if err := this.commit(operation, oldLine, newLine); err != nil {
+		return "", err
+	}
+	return newLine.Id, nil
+}
+
+// Edit records that the activity, which keeps its ID, now has the
+// timestamp, rule, priority and body it is given
+func (this *Store) Edit(activity entities.OneActivity) error {
+	thisLine, err := editLine(activity, this.now())
+	if err != nil {
+		return err
+	}
+	return this.commit("", thisLine)
+}
+
+// Grep returns the lines of the journal, in the logfile format, that
+// contain the given text.
+func (this *Store) Grep(id string) ([]string, error) {
+	loglines := []string{}
+	err := this.eachLogLine(func(thisline LogLine) {
+		line := strings.TrimSuffix(thisline.LogString(), "\n")
+		if strings.Contains(line, id) {
+			loglines = append(loglines, line)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loglines, nil
+}
@@ -0,0 +1,558 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+// openStores opens one store of each backend, in a fresh directory
+func openStores(t *testing.T) map[string]*Store {
+	dir := t.TempDir()
+	database, err := Open("kv://" + filepath.Join(dir, "acts.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return map[string]*Store{
+		"file": FileStore(filepath.Join(dir, "logfile.txt")),
+		"kv":   database,
+		"mem":  MemoryStore(),
+	}
+}
+
+func newActivity(stamp, body string) entities.OneActivity {
+	timestamp, err := time.ParseInLocation(Bformat, stamp, time.UTC)
+	if err != nil {
+		panic(err)
+	}
+	return entities.OneActivity{Timestamp: timestamp, Body: body}
+}
+
+func bodies(activities entities.Activities) string {
+	activities.Sort()
+	output := []string{}
+	for _, activity := range activities {
+		output = append(output, activity.Body)
+	}
+	return strings.Join(output, ", ")
+}
+
+func TestStore(t *testing.T) {
+	for name, store := range openStores(t) {
+		t.Run(name, func(t *testing.T) {
+			carId, err := store.AddNew(newActivity("2014-01-01 12:00", "Wash the car"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.AddNew(newActivity("2014-01-02 12:00", "Feed the cat")); err != nil {
+				t.Fatal(err)
+			}
+			found, err := store.FindActivity(carId[0:4])
+			if err != nil || len(found) != 1 || found[0].Id != carId {
+				t.Fatalf("found %v, %v, want the car", found, err)
+			}
+
+			delayedId, err := store.Replace(found[0], newActivity("2014-01-03 12:00", "Wash the car"), "delay")
+			if err != nil {
+				t.Fatal(err)
+			}
+			all, err := store.GetAll()
+			if err != nil || bodies(all) != "Feed the cat, Wash the car" {
+				t.Fatalf("got %q, %v after the delay", bodies(all), err)
+			}
+			if found, _ := store.FindActivity(carId); len(found) != 0 {
+				t.Errorf("found %v, want the delayed activity gone", found)
+			}
+
+			lines, err := store.Grep("(" + carId)
+			if err != nil || len(lines) != 2 || !strings.Contains(lines[0], "ADD: ") || !strings.Contains(lines[1], "DELETE: ") {
+				t.Errorf("grep got %q, %v, want its ADD and DELETE", lines, err)
+			}
+
+			undone, err := store.Undo(1)
+			if err != nil || len(undone) != 1 || undone[0].Name != "delay" {
+				t.Fatalf("undid %+v, %v, want the delay", undone, err)
+			}
+			if found, _ := store.FindActivity(delayedId); len(found) != 0 {
+				t.Errorf("found %v, want the delay undone", found)
+			}
+			if found, _ := store.FindActivity(carId); len(found) != 1 {
+				t.Errorf("found %v, want the car back", found)
+			}
+
+			cat, _ := store.FindActivity("")
+			for _, activity := range cat {
+				if activity.Body == "Feed the cat" {
+					if err := store.Delete(activity); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			removed, archived, err := store.Compact(true)
+			if err != nil || removed == 0 || archived == "" {
+				t.Fatalf("compact removed %d to %q, %v", removed, archived, err)
+			}
+			all, err = store.GetAll()
+			if err != nil || bodies(all) != "Wash the car" || all[0].Id != carId {
+				t.Errorf("got %v, %v after compacting, want the car with its ID", all, err)
+			}
+			if undone, err := store.Undo(1); err != nil || len(undone) != 0 {
+				t.Errorf("undid %+v, %v, want nothing before the compaction", undone, err)
+			}
+			if removed, _, err := store.Compact(false); err != nil || removed != 0 {
+				t.Errorf("compacting again removed %d, %v, want nothing", removed, err)
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		location string
+		journal  journal
+		wantErr  bool
+	}{
+		{location: filepath.Join(dir, "logfile.txt"), journal: Logfile{Filename: filepath.Join(dir, "logfile.txt")}},
+		{location: "file://" + filepath.Join(dir, "logfile.txt"), journal: Logfile{Filename: filepath.Join(dir, "logfile.txt")}},
+		{location: "file://logfile.txt", journal: Logfile{Filename: "logfile.txt"}},
+		{location: "mem://", journal: &memory{}},
+		{location: "kv://" + filepath.Join(dir, "acts.db"), journal: &database{}},
+		{location: "sql://" + filepath.Join(dir, "acts.db"), wantErr: true},
+		{location: "kv://" + filepath.Join(dir, "missing", "acts.db"), wantErr: true},
+	}
+	for _, test := range tests {
+		store, err := Open(test.location)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%q: got %T, want an error", test.location, store.journal)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", test.location, err)
+			continue
+		}
+		switch want := test.journal.(type) {
+		case Logfile:
+			if store.journal != want {
+				t.Errorf("%q: got %+v, want %+v", test.location, store.journal, want)
+			}
+		case *memory:
+			if _, ok := store.journal.(*memory); !ok {
+				t.Errorf("%q: got %T, want memory", test.location, store.journal)
+			}
+		case *database:
+			if _, ok := store.journal.(*database); !ok {
+				t.Errorf("%q: got %T, want database", test.location, store.journal)
+			}
+		}
+		store.Close()
+	}
+}
+
+func TestLogfileDropsIncompleteTransaction(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "logfile.txt")
+	store := FileStore(filename)
+	id, err := store.AddNew(newActivity("2014-01-01 12:00", "Wash the car"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a delay that stopped after its DELETE line
+	f, _ := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString("[2014-07-13T19:24:09] DELAY: 2\n")
+	f.WriteString("[2014-07-13T19:24:09] DELETE: (" + id + ") 2014-01-01 12:00 UTC Wash the car\n")
+	f.Close()
+
+	all, err := store.GetAll()
+	if err != nil || len(all) != 1 || all[0].Id != id {
+		t.Errorf("got %v, %v, want the car still there", all, err)
+	}
+
+	// the next change is not read as the rest of the delay
+	catId, err := store.AddNew(newActivity("2014-01-02 12:00", "Feed the cat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err = store.GetAll()
+	if err != nil || bodies(all) != "Wash the car, Feed the cat" {
+		t.Errorf("got %q, %v, want the car and the cat", bodies(all), err)
+	}
+	if lines, _ := store.Grep("DELAY"); len(lines) != 0 {
+		t.Errorf("got %q, want the torn delay cut off", lines)
+	}
+
+	// nor is a line that stopped part way
+	f, _ = os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString("[2014-07-13T19:24:09] DONE: (" + catId[:10])
+	f.Close()
+	if all, err := store.GetAll(); err != nil || len(all) != 2 {
+		t.Errorf("got %v, %v, want the half line left out", all, err)
+	}
+	if _, err := store.AddNew(newActivity("2014-01-03 12:00", "Water the plants")); err != nil {
+		t.Fatal(err)
+	}
+	all, err = store.GetAll()
+	if err != nil || bodies(all) != "Wash the car, Feed the cat, Water the plants" {
+		t.Errorf("got %q, %v, want all three", bodies(all), err)
+	}
+	if data, _ := os.ReadFile(filename); strings.Contains(string(data), "DONE") {
+		t.Errorf("got\n%s\nwant the half line cut off", data)
+	}
+}
+
+func TestLogfileKeepsALastLineWithNoNewline(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "logfile.txt")
+	os.WriteFile(filename, []byte("[2014-07-13T19:24:09] ADD: (414a4e) 2014-05-05 05:07 UTC Bam!"), 0600)
+	store := FileStore(filename)
+	if _, err := store.AddNew(newActivity("2014-01-01 12:00", "Wash the car")); err != nil {
+		t.Fatal(err)
+	}
+	all, err := store.GetAll()
+	if err != nil || bodies(all) != "Wash the car, Bam!" {
+		t.Errorf("got %q, %v, want both", bodies(all), err)
+	}
+}
+
+func TestLogfileParseErrorSaysWhere(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "logfile.txt")
+	os.WriteFile(filename, []byte("[2014-07-13T19:24:09] ADD: (414a4e) 2014-05-05 05:07 UTC Bam!\nnonsense\n"), 0600)
+	_, err := FileStore(filename).GetAll()
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Filename != filename || parseErr.Line != 2 {
+		t.Errorf("got %v, want a ParseError for line 2", err)
+	}
+}
+
+func TestStoreStampsChangesWithItsClock(t *testing.T) {
+	store := MemoryStore()
+	now := time.Date(2014, 7, 13, 19, 24, 9, 0, time.FixedZone("", 10*60*60))
+	store.Clock = entities.FixedClock{Time: now}
+	if _, err := store.AddNew(newActivity("2014-01-01 12:00", "Wash the car")); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := store.Grep("Wash the car")
+	if err != nil || len(lines) != 1 || !strings.HasPrefix(lines[0], "[2014-07-13T19:24:09+10:00] ADD: ") {
+		t.Errorf("got %q, %v, want the line stamped with the clock's time", lines, err)
+	}
+}
+
+func TestStoreGivesIdenticalActivitiesTheirOwnIds(t *testing.T) {
+	store := MemoryStore()
+	first, err := store.AddNew(newActivity("2014-01-01 12:00", "Wash the car"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := store.AddNew(newActivity("2014-01-01 12:00", "Wash the car"))
+	if err != nil || second == first || len(second) != 40 {
+		t.Fatalf("got IDs %q and %q, %v, want two different ones", first, second, err)
+	}
+	found, _ := store.FindActivity(first)
+	if err := store.Delete(found[0]); err != nil {
+		t.Fatal(err)
+	}
+	all, err := store.GetAll()
+	if err != nil || len(all) != 1 || all[0].Id != second {
+		t.Errorf("got %v, %v, want only the second left", all, err)
+	}
+	if err := store.Delete(newActivity("2014-01-01 12:00", "Wash the car")); err == nil {
+		t.Errorf("deleted an activity with no ID")
+	}
+}
+
+func TestMigrateSplitsSharedIds(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "logfile.txt")
+	sha := "414a4ec94c5b4c0f859b5f7cf721fceba05b4d84"
+	other := "9b1c0d0000000000000000000000000000000000"
+	os.WriteFile(filename, []byte(strings.Join([]string{
+		"[2014-07-13T19:24:09] ADD: (" + sha + ") 2014-05-05 05:07 UTC Bam!",
+		"[2014-07-13T19:25:09] ADD: (" + sha + ") 2014-05-05 05:07 UTC Bam!",
+		"[2014-07-13T19:26:09] ADD: (" + other + ") 2014-05-06 05:07 UTC Boom!",
+		"[2014-07-13T19:27:09] DELETE: (" + sha + ") 2014-05-05 05:07 UTC Bam!",
+		"[2014-07-13T19:28:09] ADD: (" + sha + ") 2014-05-05 05:07 UTC Bam!",
+		"[2014-07-13T19:29:09] ADD: (" + sha + ") 2014-05-05 05:07 UTC Bam!",
+		"",
+	}, "\n")), 0600)
+	store := FileStore(filename)
+
+	migrated, err := store.Migrate()
+	if err != nil || migrated != 2 {
+		t.Fatalf("migrated %d, %v, want 2", migrated, err)
+	}
+	all, err := store.GetAll()
+	if err != nil || bodies(all) != "Bam!, Bam!, Boom!" {
+		t.Fatalf("got %q, %v, want the two added after the delete and Boom!", bodies(all), err)
+	}
+	ids := map[string]bool{}
+	for _, activity := range all {
+		ids[activity.Id] = true
+	}
+	if len(ids) != 3 || !ids[sha] || !ids[other] {
+		t.Errorf("got IDs %v, want three, keeping the unshared ones", ids)
+	}
+	undone, err := store.Undo(3)
+	if err != nil || len(undone) != 3 || undone[2].Name != "done" || len(undone[2].Changes) != 2 {
+		t.Errorf("undid %+v, %v, want the delete of both copies last", undone, err)
+	}
+	if all, _ := store.GetAll(); bodies(all) != "Bam!, Bam!, Boom!" {
+		t.Errorf("got %q after the undo, want both copies back", bodies(all))
+	}
+	if migrated, err := store.Migrate(); err != nil || migrated != 0 {
+		t.Errorf("migrating again gave %d new IDs, %v, want none", migrated, err)
+	}
+}
+
+func TestStoreEditKeepsTheId(t *testing.T) {
+	for name, store := range openStores(t) {
+		t.Run(name, func(t *testing.T) {
+			id, err := store.AddNew(newActivity("2014-01-01 12:00", "Wash the cra"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			found, _ := store.FindActivity(id)
+			if err := store.Edit(found[0].WithText("!1 Wash the car")); err != nil {
+				t.Fatal(err)
+			}
+			for _, read := range []func() (entities.Activities, error){
+				store.GetAll,
+				func() (entities.Activities, error) { return store.FindActivity(id[0:4]) },
+			} {
+				all, err := read()
+				if err != nil || len(all) != 1 || all[0].Id != id || all[0].Body != "Wash the car" || all[0].Priority != 1 {
+					t.Errorf("got %v, %v, want the edited car with its ID", all, err)
+				}
+			}
+			lines, err := store.Grep("(" + id)
+			if err != nil || len(lines) != 2 || !strings.Contains(lines[1], "EDIT: ("+id+") 2014-01-01 12:00 UTC !1 Wash the car") {
+				t.Errorf("grep got %q, %v, want its ADD and EDIT", lines, err)
+			}
+
+			undone, err := store.Undo(1)
+			if err != nil || len(undone) != 1 || undone[0].Name != "edit" {
+				t.Fatalf("undid %+v, %v, want the edit", undone, err)
+			}
+			if all, _ := store.GetAll(); len(all) != 1 || all[0].Id != id || all[0].Body != "Wash the cra" || all[0].Priority != 0 {
+				t.Errorf("got %v, want the edit undone", all)
+			}
+
+			found, _ = store.FindActivity(id)
+			if err := store.Edit(found[0].WithText("Wash the car")); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := store.Compact(false); err != nil {
+				t.Fatal(err)
+			}
+			lines, err = store.Grep("(" + id)
+			if err != nil || len(lines) != 1 || !strings.Contains(lines[0], "ADD: ("+id+") 2014-01-01 12:00 UTC Wash the car") {
+				t.Errorf("grep got %q, %v after compacting, want the edited car added", lines, err)
+			}
+		})
+	}
+}
+
+func TestStoreTellsDoneFromCancelled(t *testing.T) {
+	for name, store := range openStores(t) {
+		t.Run(name, func(t *testing.T) {
+			carId, _ := store.AddNew(newActivity("2014-01-01 12:00", "Wash the car"))
+			catId, _ := store.AddNew(newActivity("2014-01-01 12:00", "Feed the cat"))
+			plantsId, _ := store.AddNew(newActivity("2014-01-01 12:00", "Water the plants"))
+			all, _ := store.GetAll()
+			byId := map[string]entities.OneActivity{}
+			for _, activity := range all {
+				byId[activity.Id] = activity
+			}
+			if err := store.Done(byId[carId]); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Cancel(byId[catId]); err != nil {
+				t.Fatal(err)
+			}
+			nextId, err := store.Repeat(byId[plantsId], newActivity("2014-01-08 12:00", "Water the plants"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			all, err = store.GetAll()
+			if err != nil || len(all) != 1 || all[0].Id != nextId {
+				t.Errorf("got %v, %v, want only the next occurrence", all, err)
+			}
+			for id, want := range map[string]string{carId: "DONE: (", catId: "CANCEL: (", plantsId: "DONE: ("} {
+				lines, _ := store.Grep("(" + id)
+				if len(lines) != 2 || !strings.Contains(lines[1], want+id) {
+					t.Errorf("grep got %q, want its %s", lines, want)
+				}
+			}
+
+			undone, err := store.Undo(3)
+			if err != nil || len(undone) != 3 || undone[0].Name != "repeat" || undone[1].Name != "cancel" || undone[2].Name != "done" {
+				t.Fatalf("undid %+v, %v, want the repeat, the cancel and the done", undone, err)
+			}
+			if all, _ := store.GetAll(); bodies(all) != "Feed the cat, Wash the car, Water the plants" {
+				t.Errorf("got %q after the undo, want all three back", bodies(all))
+			}
+		})
+	}
+}
+
+func TestStoreHistoryLeavesOutWhatWasUndone(t *testing.T) {
+	for name, store := range openStores(t) {
+		t.Run(name, func(t *testing.T) {
+			carId, _ := store.AddNew(newActivity("2014-01-01 12:00", "Wash the car"))
+			store.AddNew(newActivity("2014-01-02 12:00", "Feed the cat"))
+			found, _ := store.FindActivity(carId)
+			if err := store.Done(found[0]); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Undo(1); err != nil {
+				t.Fatal(err)
+			}
+			found, _ = store.FindActivity(carId)
+			if err := store.Cancel(found[0]); err != nil {
+				t.Fatal(err)
+			}
+			history, err := store.History()
+			if err != nil || len(history) != 3 {
+				t.Fatalf("got %+v, %v, want the two news and the cancel", history, err)
+			}
+			last := history[2]
+			if last.Name != "cancel" || len(last.Changes) != 1 || last.Changes[0].Command != entities.CancelCommand || last.Changes[0].Activity.Id != carId {
+				t.Errorf("got %+v last, want the car cancelled", last)
+			}
+		})
+	}
+}
+
+func TestStoreKeepsWhatAnActivityWasDelayedFrom(t *testing.T) {
+	for name, store := range openStores(t) {
+		t.Run(name, func(t *testing.T) {
+			carId, _ := store.AddNew(newActivity("2014-01-01 12:00", "Wash the car"))
+			found, _ := store.FindActivity(carId)
+			delayed := newActivity("2014-01-02 12:00", "Wash the car")
+			delayed.Predecessor = carId
+			delayedId, err := store.Replace(found[0], delayed, "delay")
+			if err != nil {
+				t.Fatal(err)
+			}
+			all, err := store.GetAll()
+			if err != nil || len(all) != 1 || all[0].Id != delayedId || all[0].Predecessor != carId {
+				t.Errorf("got %+v, %v, want the delayed car saying where it came from", all, err)
+			}
+			lines, err := store.Grep(carId)
+			if err != nil || len(lines) != 3 || !strings.Contains(lines[2], "ADD: ("+delayedId+" from "+carId+") 2014-01-02 12:00 UTC Wash the car") {
+				t.Errorf("grep got %q, %v, want the ADD naming the car it came from", lines, err)
+			}
+			history, err := store.History()
+			if err != nil || len(history) != 2 || history[1].Changes[1].Activity.Predecessor != carId {
+				t.Errorf("got %+v, %v, want the delay to say where it came from", history, err)
+			}
+		})
+	}
+}
+
+func TestStoreRefusesToChangeWhatIsAlreadyGone(t *testing.T) {
+	for name, store := range openStores(t) {
+		t.Run(name, func(t *testing.T) {
+			carId, _ := store.AddNew(newActivity("2014-01-01 12:00", "Wash the car"))
+			found, _ := store.FindActivity(carId)
+			stale := found[0]
+			if _, err := store.Replace(stale, newActivity("2014-01-02 12:00", "Wash the car"), "delay"); err != nil {
+				t.Fatal(err)
+			}
+
+			var conflict *ConflictError
+			if err := store.Done(stale); !errors.As(err, &conflict) || conflict.Id != carId {
+				t.Errorf("done got %v, want a conflict over %s", err, carId)
+			}
+			if _, err := store.Replace(stale, newActivity("2014-01-03 12:00", "Wash the car"), "delay"); !errors.As(err, &conflict) {
+				t.Errorf("delay got %v, want a conflict", err)
+			}
+			stale.Body = "Wash the van"
+			if err := store.Edit(stale); !errors.As(err, &conflict) {
+				t.Errorf("edit got %v, want a conflict", err)
+			}
+			if all, _ := store.GetAll(); len(all) != 1 || all[0].Timestamp.Day() != 2 {
+				t.Errorf("got %+v, want only the first delay", all)
+			}
+		})
+	}
+}
+
+func TestStoreDelaysOnlyOnceWhenDelayedTwiceAtOnce(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "logfile.txt")
+	carId, _ := FileStore(name).AddNew(newActivity("2014-01-01 12:00", "Wash the car"))
+	found, _ := FileStore(name).FindActivity(carId)
+
+	const tries = 8
+	results := make(chan error, tries)
+	for i := 0; i < tries; i++ {
+		go func() {
+			_, err := FileStore(name).Replace(found[0], newActivity("2014-01-02 12:00", "Wash the car"), "delay")
+			results <- err
+		}()
+	}
+	delayed := 0
+	for i := 0; i < tries; i++ {
+		var conflict *ConflictError
+		switch err := <-results; {
+		case err == nil:
+			delayed++
+		case !errors.As(err, &conflict):
+			t.Error(err)
+		}
+	}
+	if all, _ := FileStore(name).GetAll(); delayed != 1 || len(all) != 1 {
+		t.Errorf("delayed %d times leaving %+v, want it delayed once", delayed, all)
+	}
+}
+
+func TestStoreFindsWhatUndoPutBack(t *testing.T) {
+	for name, store := range openStores(t) {
+		t.Run(name, func(t *testing.T) {
+			carId, _ := store.AddNew(newActivity("2014-01-01 12:00", "Wash the car"))
+			found, _ := store.FindActivity(carId)
+			if err := store.Done(found[0]); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Undo(1); err != nil {
+				t.Fatal(err)
+			}
+
+			found, err := store.FindActivity(carId)
+			if err != nil || len(found) != 1 || found[0].Id != carId {
+				t.Fatalf("got %+v, %v, want the car back after the undo", found, err)
+			}
+			delayedId, err := store.Replace(found[0], newActivity("2014-01-02 12:00", "Wash the car"), "delay")
+			if err != nil {
+				t.Fatalf("could not delay the car put back: %v", err)
+			}
+			if _, err := store.Undo(1); err != nil {
+				t.Fatal(err)
+			}
+			if found, _ := store.FindActivity(delayedId); len(found) != 0 {
+				t.Errorf("got %+v, want the undone delay gone", found)
+			}
+
+			found, _ = store.FindActivity(carId)
+			if len(found) != 1 {
+				t.Fatalf("got %+v, want the car back after undoing the delay", found)
+			}
+			if err := store.Done(found[0]); err != nil {
+				t.Fatalf("could not mark the car put back as done: %v", err)
+			}
+			if all, err := store.GetAll(); err != nil || len(all) != 0 {
+				t.Errorf("got %+v, %v, want nothing left to do", all, err)
+			}
+		})
+	}
+}
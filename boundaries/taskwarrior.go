@@ -0,0 +1,287 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+// The activities are exchanged with Taskwarrior as the JSON of 'task
+// export' and 'task import'. The body is the description, its first
+// +project is the project and its @contexts are the tags. A repeating
+// activity is a "recurring" task, which Taskwarrior makes the occurrences
+// of itself. Taskwarrior keeps times in UTC and repeats only on a
+// schedule, so the zone and the rule exactly as acts keeps it are also
+// written, as the attributes acts_zone and acts_rtask, when they would
+// otherwise be lost. The uuid is made from the activity's ID but cannot hold
+// all of it, so the ID is also written as acts_id.
+
+const taskwarriorDate = "20060102T150405Z"
+
+type taskwarriorTask struct {
+	Uuid        string   `json:"uuid,omitempty"`
+	Status      string   `json:"status"`
+	Description string   `json:"description"`
+	Entry       string   `json:"entry,omitempty"`
+	Due         string   `json:"due,omitempty"`
+	Priority    string   `json:"priority,omitempty"`
+	Project     string   `json:"project,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Recur       string   `json:"recur,omitempty"`
+	Parent      string   `json:"parent,omitempty"`
+	ActsZone    string   `json:"acts_zone,omitempty"`
+	ActsRtask   string   `json:"acts_rtask,omitempty"`
+	ActsId      string   `json:"acts_id,omitempty"`
+}
+
+var taskwarriorPriorities = map[int]string{1: "H", 2: "M", 3: "L"}
+
+// WriteTaskwarrior writes the activities as a JSON array of tasks
+func WriteTaskwarrior(w io.Writer, activities entities.Activities) error {
+	tasks := []taskwarriorTask{}
+	for _, activity := range activities {
+		tasks = append(tasks, toTaskwarrior(activity))
+	}
+	encoded, err := json.MarshalIndent(tasks, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(encoded, '\n'))
+	return err
+}
+
+func toTaskwarrior(activity entities.OneActivity) taskwarriorTask {
+	task := taskwarriorTask{
+		Uuid:        taskwarriorUuid(activity.Id),
+		Status:      "pending",
+		Description: activity.Body,
+		Due:         activity.Timestamp.UTC().Format(taskwarriorDate),
+		Priority:    taskwarriorPriorities[activity.Priority],
+		Tags:        activity.Contexts(),
+		ActsZone:    zoneToWrite(activity),
+		ActsId:      activity.Id,
+	}
+	if projects := activity.Projects(); len(projects) > 0 {
+		task.Project = projects[0]
+	}
+	if activity.HasRepeatCommand() {
+		task.Status = "recurring"
+		rule, err := entities.ParseRepeatRule(activity.CommandTag)
+		if err == nil {
+			task.Recur = taskwarriorRecur(rule)
+		}
+		if err != nil || !rule.Fixed || rule.From != "" {
+			task.ActsRtask = activity.CommandTag
+		}
+	}
+	return task
+}
+
+// taskwarriorUuid makes a UUID from the start of the ID, so that the same
+// activity is the same task however often it is exported
+func taskwarriorUuid(id string) string {
+	if len(id) < 32 {
+		return ""
+	}
+	hex := []byte(strings.ToLower(id[0:32]))
+	hex[12] = '5'
+	hex[16] = "89ab"[strings.IndexByte("0123456789abcdef", hex[16])%4]
+	return fmt.Sprintf("%s-%s-%s-%s-%s", hex[0:8], hex[8:12], hex[12:16], hex[16:20], hex[20:32])
+}
+
+var taskwarriorUnits = map[string]string{"hours": "h", "days": "d", "weeks": "w", "months": "mo"}
+var taskwarriorNamedRecurs = map[string]string{"hours": "hourly", "days": "daily", "weeks": "weekly", "months": "monthly"}
+
+func taskwarriorRecur(rule entities.RepeatRule) string {
+	if rule.Count == 1 {
+		return taskwarriorNamedRecurs[rule.Unit]
+	}
+	return fmt.Sprintf("%d%s", rule.Count, taskwarriorUnits[rule.Unit])
+}
+
+// ReadTaskwarrior reads the JSON of 'task export', either an array or one
+// task to a line, as activities, as the storage would read them back, with
+// the ID in acts_id if WriteTaskwarrior wrote one. A task is due at its due date, or else when it was entered, or
+// else now. Completed and deleted tasks are left out, and so is a recurring
+// task when one of its occurrences is there to be read instead.
+func ReadTaskwarrior(r io.Reader, now time.Time) (entities.Activities, error) {
+	tasks, err := decodeTaskwarrior(r)
+	if err != nil {
+		return nil, err
+	}
+	occurring := map[string]bool{}
+	for _, task := range tasks {
+		if task.Parent != "" && task.Status == "pending" {
+			occurring[task.Parent] = true
+		}
+	}
+	output := entities.Activities{}
+	for _, task := range tasks {
+		if task.Status != "pending" && task.Status != "waiting" && task.Status != "recurring" {
+			continue
+		}
+		if task.Status == "recurring" && occurring[task.Uuid] {
+			continue
+		}
+		activity, err := fromTaskwarrior(task, now)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read the task %q: %s", task.Description, err)
+		}
+		output = append(output, activity)
+	}
+	return output, nil
+}
+
+func decodeTaskwarrior(r io.Reader) ([]taskwarriorTask, error) {
+	reader := bufio.NewReader(r)
+	tasks := []taskwarriorTask{}
+	for {
+		next, err := reader.Peek(1)
+		if err == io.EOF {
+			return tasks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch next[0] {
+		case ' ', '\t', '\r', '\n', ',':
+			reader.ReadByte()
+			continue
+		}
+		decoder := json.NewDecoder(reader)
+		if next[0] == '[' {
+			return tasks, decoder.Decode(&tasks)
+		}
+		for decoder.More() {
+			var task taskwarriorTask
+			if err := decoder.Decode(&task); err != nil {
+				return nil, err
+			}
+			tasks = append(tasks, task)
+		}
+		return tasks, nil
+	}
+}
+
+func fromTaskwarrior(task taskwarriorTask, now time.Time) (entities.OneActivity, error) {
+	loc := entities.Zone
+	if task.ActsZone != "" {
+		zone, err := entities.LoadZone(task.ActsZone)
+		if err != nil {
+			return entities.OneActivity{}, err
+		}
+		loc = zone
+	}
+	stamp := now
+	for _, when := range []string{task.Entry, task.Due} {
+		if when == "" {
+			continue
+		}
+		parsed, err := time.Parse(taskwarriorDate, when)
+		if err != nil {
+			return entities.OneActivity{}, err
+		}
+		stamp = parsed
+	}
+
+	activity := entities.OneActivity{
+		Timestamp: stamp.In(loc).Truncate(time.Minute),
+		Body:      task.Description,
+		Priority:  map[string]int{"H": 1, "M": 2, "L": 3}[task.Priority],
+	}
+	words := strings.Fields(task.Description)
+	if task.Project != "" && !containsWord(words, "+"+task.Project) {
+		activity.Body += " +" + task.Project
+	}
+	for _, tag := range task.Tags {
+		if !containsWord(words, "@"+tag) {
+			activity.Body += " @" + tag
+		}
+	}
+	activity.Body = strings.TrimSpace(activity.Body)
+	activity.CommandTag = task.ActsRtask
+	if activity.CommandTag == "" && task.Recur != "" {
+		activity.CommandTag = repeatTagFromRecur(task.Recur)
+	}
+	stored, err := storedForm(activity)
+	if idPattern.MatchString(task.ActsId) {
+		stored.Id = task.ActsId
+	}
+	return stored, err
+}
+
+func containsWord(words []string, word string) bool {
+	for _, each := range words {
+		if each == word {
+			return true
+		}
+	}
+	return false
+}
+
+var taskwarriorRecurPattern = regexp.MustCompile(`^(\d*)\s*(h|hrs?|hours?|d|days?|w|wks?|weeks?|mo|mos|mths?|months?|q|qtrs?|quarters?|y|yrs?|years?)$`)
+var isoRecurPattern = regexp.MustCompile(`^P(?:(\d+)Y|(\d+)M|(\d+)W|(\d+)D|T(\d+)H)$`)
+
+// taskwarriorRecurNames are the names Taskwarrior gives to some periods,
+// as a count and a unit it also understands
+var taskwarriorRecurNames = map[string]string{
+	"hourly": "1h", "daily": "1d", "weekly": "1w", "biweekly": "2w", "fortnight": "2w",
+	"monthly": "1mo", "bimonthly": "2mo", "quarterly": "1q", "semiannual": "6mo",
+	"annual": "1y", "yearly": "1y", "biannual": "2y", "biyearly": "2y",
+}
+
+// repeatTagFromRecur is the tag for a Taskwarrior recur such as "daily",
+// "3d" or "P2W", or "" for one that acts cannot follow
+func repeatTagFromRecur(recur string) string {
+	recur = strings.ToLower(recur)
+	if named, ok := taskwarriorRecurNames[recur]; ok {
+		recur = named
+	}
+	if match := isoRecurPattern.FindStringSubmatch(strings.ToUpper(recur)); match != nil {
+		for i, unit := range []string{"y", "mo", "w", "d", "h"} {
+			if match[i+1] != "" {
+				recur = match[i+1] + unit
+			}
+		}
+	}
+	match := taskwarriorRecurPattern.FindStringSubmatch(recur)
+	if match == nil {
+		return ""
+	}
+	count := 1
+	if match[1] != "" {
+		count, _ = strconv.Atoi(match[1])
+	}
+	if count < 1 {
+		return ""
+	}
+	unit := ""
+	switch match[2][0] {
+	case 'h':
+		unit = "hours"
+	case 'd':
+		unit = "days"
+	case 'w':
+		unit = "weeks"
+	case 'm':
+		unit = "months"
+	case 'q':
+		unit, count = "months", count*3
+	case 'y':
+		unit, count = "months", count*12
+	}
+	return fmt.Sprintf("every-n-%s:%d", unit, count)
+}
@@ -0,0 +1,100 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+func TestTaskwarriorRoundTrip(t *testing.T) {
+	entities.Zone = time.UTC
+	live := exchangeFixture(t)
+	var buffer bytes.Buffer
+	if err := WriteTaskwarrior(&buffer, live); err != nil {
+		t.Fatal(err)
+	}
+	written := buffer.String()
+	for _, want := range []string{
+		`"status": "recurring"`,
+		`"recur": "daily"`,
+		`"acts_rtask": "after-n-days:1"`,
+		`"due": "20140102T083000Z"`,
+		`"acts_zone": "Europe/Berlin"`,
+		`"priority": "H"`,
+		`"project": "admin"`,
+		`"recur": "2w"`,
+		`"recur": "monthly"`,
+		`"uuid": "` + taskwarriorUuid(live[0].Id) + `"`,
+	} {
+		if !strings.Contains(written, want) {
+			t.Errorf("got\n%s\nwant it to contain %s", written, want)
+		}
+	}
+	read, err := ReadTaskwarrior(&buffer, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameActivities(t, read, live)
+	sameIds(t, read, live)
+}
+
+func TestReadTaskwarriorFromElsewhere(t *testing.T) {
+	entities.Zone = time.UTC
+	now := newActivity("2014-02-01 10:20", "").Timestamp
+	input := strings.Join([]string{
+		`{"uuid":"a","status":"completed","description":"Done already","due":"20140101T120000Z"}`,
+		`{"uuid":"b","status":"recurring","description":"Stand-up","due":"20140101T090000Z","recur":"weekdays"}`,
+		`{"uuid":"c","status":"pending","description":"Stand-up","due":"20140103T090000Z","recur":"weekdays","parent":"b"}`,
+		`{"uuid":"d","status":"recurring","description":"Backup","due":"20140104T020000Z","recur":"P2W","project":"ops","tags":["server","night"]}`,
+		`{"uuid":"e","status":"waiting","description":"Renew +car rego","entry":"20140105T101010Z","priority":"M","project":"car"}`,
+		`{"uuid":"f","status":"pending","description":"Someday"}`,
+	}, "\n")
+	read, err := ReadTaskwarrior(strings.NewReader(input), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, activity := range read {
+		got = append(got, activity.FullString())
+	}
+	want := []string{
+		"2014-01-03 09:00 UTC Stand-up",
+		"2014-01-04 02:00 UTC @rtask:every-n-weeks:2 Backup +ops @server @night",
+		"2014-01-05 10:10 UTC !2 Renew +car rego",
+		"2014-02-01 10:20 UTC Someday",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := ReadTaskwarrior(strings.NewReader(`[{"status":"pending","due":"tomorrow"}]`), now); err == nil {
+		t.Error("got no error for a due that is not a time")
+	}
+}
+
+func TestRepeatTagFromRecur(t *testing.T) {
+	for recur, want := range map[string]string{
+		"daily":     "every-n-days:1",
+		"3d":        "every-n-days:3",
+		"2 weeks":   "every-n-weeks:2",
+		"quarterly": "every-n-months:3",
+		"6mo":       "every-n-months:6",
+		"P1Y":       "every-n-months:12",
+		"PT4H":      "every-n-hours:4",
+		"weekdays":  "",
+		"0d":        "",
+	} {
+		if got := repeatTagFromRecur(recur); got != want {
+			t.Errorf("%q: got %q, want %q", recur, got, want)
+		}
+	}
+}
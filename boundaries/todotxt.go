@@ -0,0 +1,249 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+// The activities are exchanged with todo.txt as one line each:
+//
+//	(A) Renew the passport +admin @town due:2014-01-02 at:09:30 rec:+1w
+//
+// The priorities !1 to !3 are (A) to (C). The body is the description, so
+// its +projects and @contexts are todo.txt's own. todo.txt keeps only the
+// date in due:, so the time is kept in at:, and the zone in tz: when it is
+// not the configured Zone. A rule that repeats on a schedule is rec:+NU and
+// one that counts from when it is done is rec:NU, with U one of h, d, w, m
+// or y; a rule with a 'from' time is also kept whole in rtask:.
+//
+// A body that todo.txt would read as done, as a priority or as a creation
+// date, such as "x marks the spot", is written after a creation date, the
+// day it is due, so that it is read back as it was. A key:value word is
+// only read as one of the fields above if its value is one acts can use,
+// and only the last of them for each key, so "due:soon" stays in the body,
+// and at: and tz: are written whenever the body has a word that would be
+// read as one of them. A body word such as "rec:+1w" in an activity that
+// does not repeat would still be read as its rule.
+
+var todoPriorities = map[int]string{1: "A", 2: "B", 3: "C"}
+
+var todoUnits = map[string]string{"hours": "h", "days": "d", "weeks": "w", "months": "m"}
+
+// WriteTodoTxt writes the activities as a todo.txt file
+func WriteTodoTxt(w io.Writer, activities entities.Activities) error {
+	writer := bufio.NewWriter(w)
+	for _, activity := range activities {
+		writer.WriteString(todoLine(activity) + "\n")
+	}
+	return writer.Flush()
+}
+
+func todoLine(activity entities.OneActivity) string {
+	words := []string{}
+	if priority, ok := todoPriorities[activity.Priority]; ok {
+		words = append(words, "("+priority+")")
+	}
+	if looksLikeTodoPrefix(activity.Body) {
+		words = append(words, activity.Timestamp.Format("2006-01-02"))
+	}
+	if activity.Body != "" {
+		words = append(words, activity.Body)
+	}
+	words = append(words, "due:"+activity.Timestamp.Format("2006-01-02"))
+	if clock := activity.Timestamp.Format("15:04"); clock != "00:00" || hasTodoField(activity.Body, "at") {
+		words = append(words, "at:"+clock)
+	}
+	if zone := zoneToWrite(activity); zone != "" {
+		words = append(words, "tz:"+zone)
+	} else if hasTodoField(activity.Body, "tz") {
+		words = append(words, "tz:"+entities.Zone.String())
+	}
+	if activity.HasRepeatCommand() {
+		rule, err := entities.ParseRepeatRule(activity.CommandTag)
+		if err == nil {
+			strict := ""
+			if rule.Fixed {
+				strict = "+"
+			}
+			words = append(words, fmt.Sprintf("rec:%s%d%s", strict, rule.Count, todoUnits[rule.Unit]))
+		}
+		if err != nil || rule.From != "" {
+			words = append(words, "rtask:"+activity.CommandTag)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// looksLikeTodoPrefix says whether the body starts with what todo.txt reads
+// at the start of a line: "x " for done, a priority or a creation date
+func looksLikeTodoPrefix(body string) bool {
+	body += " "
+	return strings.HasPrefix(body, "x ") || todoPriorityPattern.MatchString(body) || todoDatePattern.MatchString(body)
+}
+
+var todoPriorityPattern = regexp.MustCompile(`^\(([A-Z])\) `)
+var todoDatePattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}) `)
+var todoRecPattern = regexp.MustCompile(`^(\+?)(\d+)([hdwmy])$`)
+
+// ReadTodoTxt reads the tasks of a todo.txt file as activities, without
+// IDs, as the storage would read them back. A task is due at its due: date
+// and at: time, or else at the start of the day it was created, or else
+// now. Tasks that are done are left out, and key:value words that acts has
+// no use for are left in the body.
+func ReadTodoTxt(r io.Reader, now time.Time) (entities.Activities, error) {
+	output := entities.Activities{}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "x ") {
+			continue
+		}
+		activity, err := todoActivity(line, now)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read todo.txt line %d %q: %s", lineNumber, line, err)
+		}
+		output = append(output, activity)
+	}
+	return output, scanner.Err()
+}
+
+func todoActivity(line string, now time.Time) (entities.OneActivity, error) {
+	activity := entities.OneActivity{}
+	if match := todoPriorityPattern.FindStringSubmatch(line); match != nil {
+		activity.Priority = 1 + int(match[1][0]-'A')
+		if activity.Priority > 3 {
+			activity.Priority = 3
+		}
+		line = line[len(match[0]):]
+	}
+	created := ""
+	if match := todoDatePattern.FindStringSubmatch(line); match != nil {
+		created = match[1]
+		line = line[len(match[0]):]
+	}
+
+	words := strings.Fields(line)
+	last := map[string]int{}
+	for i, word := range words {
+		if key, value, found := strings.Cut(word, ":"); found && isTodoField(key, value) {
+			last[key] = i
+		}
+	}
+	fields := map[string]string{"at": "00:00"}
+	body := []string{}
+	for i, word := range words {
+		key, value, _ := strings.Cut(word, ":")
+		if at, ok := last[key]; ok && at == i {
+			fields[key] = value
+		} else {
+			body = append(body, word)
+		}
+	}
+	due, at, zone, rec, rtask := fields["due"], fields["at"], fields["tz"], repeatTagFromRec(fields["rec"]), fields["rtask"]
+	activity.Body = strings.Join(body, " ")
+	activity.CommandTag = rec
+	if rtask != "" {
+		activity.CommandTag = rtask
+	}
+
+	loc := entities.Zone
+	if zone != "" {
+		named, err := entities.LoadZone(zone)
+		if err != nil {
+			return entities.OneActivity{}, err
+		}
+		loc = named
+	}
+	switch {
+	case due != "":
+		stamp, err := time.ParseInLocation("2006-01-02 15:04", due+" "+at, loc)
+		if err != nil {
+			return entities.OneActivity{}, err
+		}
+		activity.Timestamp = stamp
+	case created != "":
+		stamp, err := time.ParseInLocation("2006-01-02", created, loc)
+		if err != nil {
+			return entities.OneActivity{}, err
+		}
+		activity.Timestamp = stamp
+	default:
+		activity.Timestamp = now.In(loc).Truncate(time.Minute)
+	}
+	return storedForm(activity)
+}
+
+// hasTodoField says whether a word of the body would be read as the field,
+// so that the field has to be written after it even if it has the value
+// that goes without saying
+func hasTodoField(body, key string) bool {
+	for _, word := range strings.Fields(body) {
+		if wordKey, value, found := strings.Cut(word, ":"); found && wordKey == key && isTodoField(key, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTodoField says whether a key:value word is one of the fields acts reads,
+// with a value it can use
+func isTodoField(key, value string) bool {
+	var err error
+	switch key {
+	case "due":
+		_, err = time.Parse("2006-01-02", value)
+	case "at":
+		_, err = time.Parse("15:04", value)
+	case "tz":
+		_, err = entities.LoadZone(value)
+	case "rec":
+		return repeatTagFromRec(value) != ""
+	case "rtask":
+		_, err = entities.ParseRepeatRule(value)
+	default:
+		return false
+	}
+	return err == nil && value != ""
+}
+
+// repeatTagFromRec is the tag for a rec: such as "+1w" or "3d", or "" for
+// one that acts cannot follow
+func repeatTagFromRec(rec string) string {
+	match := todoRecPattern.FindStringSubmatch(rec)
+	if match == nil {
+		return ""
+	}
+	kind := "after"
+	if match[1] == "+" {
+		kind = "every"
+	}
+	count, err := strconv.Atoi(match[2])
+	if err != nil || count < 1 {
+		return ""
+	}
+	unit := match[3]
+	if unit == "y" {
+		count, unit = count*12, "m"
+	}
+	for name, short := range todoUnits {
+		if short == unit {
+			unit = name
+		}
+	}
+	return fmt.Sprintf("%s-n-%s:%d", kind, unit, count)
+}
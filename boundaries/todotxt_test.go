@@ -0,0 +1,146 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+// exchangeFixture is a stream of activities with everything the formats
+// can carry, as they are read back from storage
+func exchangeFixture(t *testing.T) entities.Activities {
+	store := MemoryStore()
+	for _, line := range []string{
+		"2014-01-01 12:00 @rtask:after-n-days:1 Duolingo",
+		"2014-01-02 09:30 Europe/Berlin !1 Renew the passport +admin @town",
+		"2014-01-03 00:00 @rtask:every-n-weeks:2:from-08:00 !3 Water the plants @home",
+		"2014-01-04 08:00 @rtask:every-n-months:1 Pay rent",
+	} {
+		activity, err := entities.ParseOneActivity(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.AddNew(activity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	live, _ := store.GetAll()
+	live.Sort()
+	return live
+}
+
+// sameIds checks that the activities read back have the IDs they were
+// written with
+func sameIds(t *testing.T, got, want entities.Activities) {
+	for i := range want {
+		if i < len(got) && got[i].Id != want[i].Id {
+			t.Errorf("read %q with ID %q, want %q", got[i].Body, got[i].Id, want[i].Id)
+		}
+	}
+}
+
+func sameActivities(t *testing.T, got, want entities.Activities) {
+	if len(got) != len(want) {
+		t.Fatalf("read %d activities, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].FullString() != want[i].FullString() {
+			t.Errorf("read %q, want %q", got[i].FullString(), want[i].FullString())
+		}
+	}
+}
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	entities.Zone = time.UTC
+	live := exchangeFixture(t)
+	var buffer bytes.Buffer
+	if err := WriteTodoTxt(&buffer, live); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"Duolingo due:2014-01-01 at:12:00 rec:1d",
+		"(A) Renew the passport +admin @town due:2014-01-02 at:09:30 tz:Europe/Berlin",
+		"(C) Water the plants @home due:2014-01-03 rec:+2w rtask:every-n-weeks:2:from-08:00",
+		"Pay rent due:2014-01-04 at:08:00 rec:+1m",
+	}, "\n") + "\n"
+	if buffer.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buffer.String(), want)
+	}
+	read, err := ReadTodoTxt(&buffer, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameActivities(t, read, live)
+
+	// bodies that todo.txt could read as something else
+	tricky := entities.Activities{}
+	for _, line := range []string{
+		"2014-01-01 12:00 x marks the spot",
+		"2014-01-01 12:00 x",
+		"2014-01-01 00:00 2014-05-05 was the last service",
+		"2014-01-01 12:00 (A) is a grade, not a priority",
+		"2014-01-01 12:00 !2 (B) after a priority",
+		"2014-01-01 12:00 !1 2014-05-05 after a priority",
+		"2014-01-01 12:00 Ship it due:soon at:the-office tz:Mars/Olympus rtask:whenever",
+		"2014-01-01 12:00 Ship it rec:0d due:2014-02-02",
+		"2014-01-01 00:00 Meet at:10:30 in the lobby",
+		"2014-01-01 12:00 Call Berlin tz:Europe/Berlin",
+	} {
+		activity, err := entities.ParseOneActivity(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tricky = append(tricky, activity)
+	}
+	buffer.Reset()
+	if err := WriteTodoTxt(&buffer, tricky); err != nil {
+		t.Fatal(err)
+	}
+	read, err = ReadTodoTxt(&buffer, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameActivities(t, read, tricky)
+}
+
+func TestReadTodoTxtFromElsewhere(t *testing.T) {
+	entities.Zone = time.UTC
+	now := newActivity("2014-02-01 10:20", "").Timestamp
+	input := strings.Join([]string{
+		"x 2014-01-01 Already done due:2014-01-01",
+		"",
+		"(D) 2014-01-05 Call Mom +family @phone due:2014-01-10 rec:+1y",
+		"2014-01-06 Read the book url:http://example.com rec:0d",
+		"Someday",
+	}, "\n")
+	read, err := ReadTodoTxt(strings.NewReader(input), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, activity := range read {
+		got = append(got, activity.FullString())
+	}
+	want := []string{
+		"2014-01-10 00:00 UTC @rtask:every-n-months:12 !3 Call Mom +family @phone",
+		"2014-01-06 00:00 UTC Read the book url:http://example.com rec:0d",
+		"2014-02-01 10:20 UTC Someday",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	read, err = ReadTodoTxt(strings.NewReader("Pay rent due:2014-13-01 due:2014-03-01 due:tomorrow"), now)
+	if err != nil || len(read) != 1 || read[0].FullString() != "2014-03-01 00:00 UTC Pay rent due:2014-13-01 due:tomorrow" {
+		t.Errorf("got %v, %v, want only the last good due: read as the date", read, err)
+	}
+}
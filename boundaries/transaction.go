@@ -0,0 +1,103 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import (
+	"strings"
+	"time"
+)
+
+// A transaction is a group of lines that were written together. In the
+// logfile format, when there is more than one they are preceded by a header
+// line naming the operation and saying how many lines follow:
+//
+//	[2014-07-13T19:24:09] DELAY: 2
+//	[2014-07-13T19:24:09] DELETE: (414a4e...) 2014-05-05 05:07 Australia/Melbourne Bam!
+//	[2014-07-13T19:24:09] ADD: (9b1c0d...) 2014-05-06 05:07 Australia/Melbourne Bam!
+//
+// A line written on its own is a transaction with no name.
+type transaction struct {
+	Name  string
+	Lines []LogLine
+}
+
+// newTransaction groups the lines under the upper-cased operation name
+func newTransaction(operation string, lines ...LogLine) transaction {
+	return transaction{Name: strings.ToUpper(operation), Lines: lines}
+}
+
+// header is the line that goes before the others, if there is one
+func (this transaction) header() (LogLine, bool) {
+	if len(this.Lines) > 1 || this.Name != "" {
+		return LogLine{Now: this.Lines[0].Now, Command: this.Name, Size: len(this.Lines)}, true
+	}
+	return LogLine{}, false
+}
+
+// LogStrings is the transaction in the logfile format, one line each
+// without the newline, header first
+func (this transaction) LogStrings() []string {
+	output := []string{}
+	if header, ok := this.header(); ok {
+		output = append(output, strings.TrimSuffix(header.LogString(), "\n"))
+	}
+	for _, line := range this.Lines {
+		output = append(output, strings.TrimSuffix(line.LogString(), "\n"))
+	}
+	return output
+}
+
+// A journal is where a Store keeps its transactions, oldest first.
+// Each backend is a journal; the Store does the rest.
+type journal interface {
+	// lock waits until no one else is writing the journal, and if
+	// exclusive is true until no one else is reading it either.
+	// It returns the function that releases the lock.
+	lock(exclusive bool) (func(), error)
+	// readTransactions calls the given function with each transaction
+	// in turn, leaving out any that was not completely written.
+	// The caller must hold a lock.
+	readTransactions(each func(transaction) error) error
+	// appendTransaction writes the whole transaction or none of it.
+	// The caller must hold the exclusive lock.
+	appendTransaction(t transaction) error
+	// replaceWith throws away every transaction and keeps only the given
+	// ones, so that readers see either all the old or all the new.
+	// The caller must hold the exclusive lock.
+	replaceWith(kept []transaction) error
+	// archive keeps the lines, in the logfile format, somewhere aside
+	// and says where.
+	archive(lines []string, now time.Time) (string, error)
+	close() error
+}
+
+// transactionReader puts lines read one at a time back together into
+// transactions, passing each to the function when it is complete.
+type transactionReader struct {
+	each      func(transaction) error
+	current   *transaction
+	remaining int
+}
+
+func (this *transactionReader) add(thisline LogLine) error {
+	if thisline.Size > 0 {
+		this.current = &transaction{Name: thisline.Command}
+		this.remaining = thisline.Size
+		return nil
+	}
+	if this.current == nil {
+		return this.each(transaction{Lines: []LogLine{thisline}})
+	}
+	this.current.Lines = append(this.current.Lines, thisline)
+	this.remaining--
+	if this.remaining > 0 {
+		return nil
+	}
+	complete := *this.current
+	this.current = nil
+	return this.each(complete)
+}
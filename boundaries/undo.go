@@ -0,0 +1,116 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package boundaries
+
+import (
+	"strings"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+const (
+	undoOperation    = "undo"
+	compactOperation = "compact"
+)
+
+// Undo reverts the last n operations that have not already been undone,
+// most recent first, and returns them. Each is reverted by appending an
+// "UNDO" transaction that adds back what it deleted and deletes what it
+// added. Undo does not reach back past a Compact.
+func (this *Store) Undo(n int) ([]entities.Operation, error) {
+	unlock, err := this.journal.lock(true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	operations, err := this.operations()
+	if err != nil {
+		return nil, err
+	}
+
+	undone := []entities.Operation{}
+	for i := len(operations) - 1; i >= 0 && len(undone) < n; i-- {
+		if operations[i].Name == compactOperation {
+			break
+		}
+		lines := []LogLine{}
+		now := this.now()
+		for _, change := range operations[i].Inverse() {
+			lines = append(lines, LogLine{Id: change.Activity.Id, Now: now, Command: change.Command, Activity: change.Activity})
+		}
+		if err := this.journal.appendTransaction(newTransaction(undoOperation, lines...)); err != nil {
+			return undone, err
+		}
+		undone = append(undone, operations[i])
+	}
+	return undone, nil
+}
+
+// History is the operations in the journal, oldest first, leaving out
+// those that have been undone and the undoing of them
+func (this *Store) History() ([]entities.Operation, error) {
+	unlock, err := this.journal.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return this.operations()
+}
+
+// operations reads the operations in the journal, oldest first, leaving out
+// those that have been undone and the undoing of them.
+// The caller must hold a lock.
+func (this *Store) operations() ([]entities.Operation, error) {
+	stack := []entities.Operation{}
+	versions := map[string]entities.OneActivity{}
+	err := this.journal.readTransactions(func(t transaction) error {
+		operation := t.operation(versions)
+		if operation.Name == undoOperation {
+			if len(stack) > 0 {
+				stack = stack[0 : len(stack)-1]
+			}
+			return nil
+		}
+		stack = append(stack, operation)
+		return nil
+	})
+	return stack, err
+}
+
+// operation names a transaction of a single line for what the user did.
+// versions holds each activity as the lines before this transaction left
+// it, so that an edit can say what it changed; it is brought up to date.
+func (this transaction) operation(versions map[string]entities.OneActivity) entities.Operation {
+	name := strings.ToLower(this.Name)
+	if name == "" && len(this.Lines) == 1 {
+		switch this.Lines[0].Command {
+		case entities.AddCommand:
+			name = "new"
+		case entities.DoneCommand:
+			name = "done"
+		case entities.CancelCommand:
+			name = "cancel"
+		case entities.DeleteCommand:
+			// until they were told apart, DELETE was written for both
+			name = "done"
+		case entities.EditCommand:
+			name = "edit"
+		}
+	}
+	changes := []entities.Change{}
+	for _, line := range this.Lines {
+		change := entities.Change{Command: line.Command, Activity: line.Activity}
+		if line.Command == entities.EditCommand {
+			change.Previous = versions[line.Id]
+		}
+		versions[line.Id] = line.Activity
+		changes = append(changes, change)
+	}
+	return entities.Operation{Name: name, Now: this.Lines[0].Now, Changes: changes}
+}
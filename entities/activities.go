@@ -16,82 +16,158 @@ import (
 
 type OneActivity struct {
 	Id         string
+	ShortId    string
 	Timestamp  time.Time
 	CommandTag string
-	Body       string
+	// Priority is 1 for the most urgent down to 3, or 0 for none
+	Priority int
+	Body     string
+	// Predecessor is the ID of the activity this one was delayed or
+	// rescheduled from, if it was
+	Predecessor string
 }
 
+// Priorities are written "!1" to "!3" before the body
+const lowestPriority = 3
+
 func (this OneActivity) IndexedString() string {
 	star := ""
 	if this.HasRepeatCommand() {
 		star = "*"
 	}
-	return fmt.Sprintf("[\033[1m%s\033[0m]%s %s %s", this.Id, star, this.TimeString(), this.Body)
+	return fmt.Sprintf("[\033[1m%s\033[0m]%s %s %s%s", this.DisplayId(), star, this.TimeString(), this.PriorityString(), this.Body)
+}
+
+// DisplayId is the ShortId if a use case has worked one out, otherwise the Id
+func (this OneActivity) DisplayId() string {
+	if this.ShortId != "" {
+		return this.ShortId
+	}
+	return this.Id
 }
 
 func (this OneActivity) String() string {
 	return fmt.Sprintf("%s %s", this.TimeString(), this.Body)
 }
 
-// Antipattern: Having code for something you aren't yet implementing...
+// PriorityString is "!1 " to "!3 ", as written before the body, or ""
+func (this OneActivity) PriorityString() string {
+	if this.Priority == 0 {
+		return ""
+	}
+	return fmt.Sprintf("!%d ", this.Priority)
+}
+
+// A repeating activity carries its RepeatRule in the CommandTag
 func (this OneActivity) HasRepeatCommand() bool {
 	return this.CommandTag != ""
 }
 
+// TimeString is the timestamp as seen from the configured Zone
 func (this OneActivity) TimeString() string {
-	return this.Timestamp.Format("2006-01-02 15:04")
+	return this.Timestamp.In(Zone).Format("2006-01-02 15:04")
 }
 
+// FullString is the form that ParseOneActivity reads back into this activity.
+// The timestamp is written in its own zone, followed by the name of the zone.
 func (this OneActivity) FullString() string {
-	return fmt.Sprintf("%s %s %s", this.TimeString(), this.CommandTag, this.Body)
+	stamp := fmt.Sprintf("%s %s", this.Timestamp.Format("2006-01-02 15:04"), zoneName(this.Timestamp))
+	return fmt.Sprintf("%s %s", stamp, this.Text())
+}
+
+// Text is everything but the timestamp, as written after it: the
+// "@rtask:" if there is a rule, the priority and the body
+func (this OneActivity) Text() string {
+	if !this.HasRepeatCommand() {
+		return this.PriorityString() + this.Body
+	}
+	return fmt.Sprintf("@rtask:%s %s%s", this.CommandTag, this.PriorityString(), this.Body)
 }
 
+// WithText is the activity with the rule, priority and body read from text
+// written as Text writes them. Its ID and timestamp stay as they were.
+func (this OneActivity) WithText(text string) OneActivity {
+	this.CommandTag, this.Priority, this.Body = parseText(text)
+	return this
+}
+
+// This reads activities as they are stored, which always name their zone.
+// What a person types is read with ParseWhen and WithText instead, so that
+// a body starting with a word such as "UTC" is not taken for a zone.
+//
 // Expected input:
-//    "YYYY-MM-DD HH:MM @rtask:every-n-hours:48 SRS a headline in Italian"
-// The "@rtask:" is optional
+//    "YYYY-MM-DD HH:MM Europe/Rome @rtask:every-n-hours:48 !2 SRS a headline in Italian"
+// The zone, the "@rtask:" and the priority are optional
 // Output is a OneActivity struct with:
-//    - a go timestamp corresponding to YYYY-MM-DD MM:DD in Melbourne
+//    - a go timestamp corresponding to YYYY-MM-DD MM:DD in the zone if it is
+//        there, otherwise in the configured Zone
 //    - everything between "@rtask:" and the next space character if the rtask
 //        is there ("" if it is not)
-//    - everything after the rtask if it is there otherwise everything after
-//        the timestamp
+//    - the priority from "!1" to "!3" if it is there (0 if it is not)
+//    - everything after the rtask and priority if they are there otherwise
+//        everything after the timestamp
 // Throws an error if the timestamp cannot be parsed
 //
 func ParseOneActivity(input string) (OneActivity, error) {
-	loc, _ := time.LoadLocation("Australia/Melbourne")
+	return ParseOneActivityIn(input, Zone)
+}
+
+// ParseOneActivityIn is ParseOneActivity with the zone to assume when the
+// input doesn't name one
+func ParseOneActivityIn(input string, loc *time.Location) (OneActivity, error) {
+	if len(input) < 17 {
+		return OneActivity{}, fmt.Errorf("Expected 'YYYY-MM-DD HH:MM text' but got '%s'", input)
+	}
+	rest := input[17:len(input)]
+	if spaceIndex := strings.Index(rest, " "); spaceIndex > 0 && isZoneName(rest[0:spaceIndex]) {
+		loc, _ = LoadZone(rest[0:spaceIndex])
+		rest = rest[spaceIndex+1 : len(rest)]
+	}
 	stamp, err := time.ParseInLocation("2006-01-02 15:04", input[0:16], loc)
-	commandTag := ""
-	body := ""
 	if err != nil {
 		return OneActivity{}, err
 	}
+	commandTag, priority, body := parseText(rest)
 
-	if len(input) > 23 && input[17:23] == "@rtask" {
-		spaceIndex := strings.Index(input[24:len(input)], " ") + 24
-		if spaceIndex > 23 {
-			commandTag = input[24:spaceIndex]
-			body = input[spaceIndex+1 : len(input)]
+	return OneActivity{
+		Timestamp:  stamp,
+		CommandTag: commandTag,
+		Priority:   priority,
+		Body:       body,
+	}, nil
+}
+
+// parseText splits what follows the timestamp into the rule, the priority
+// and the body
+func parseText(rest string) (string, int, string) {
+	commandTag := ""
+	body := ""
+	if len(rest) > 6 && rest[0:6] == "@rtask" {
+		spaceIndex := strings.Index(rest[7:len(rest)], " ") + 7
+		if spaceIndex > 6 {
+			commandTag = rest[7:spaceIndex]
+			body = rest[spaceIndex+1 : len(rest)]
 		} else {
-			body = input[17:len(input)]
+			body = rest
 		}
 	} else {
-		body = input[17:len(input)]
+		body = rest
 	}
-
-	return OneActivity{
-		"",
-		stamp,
-		commandTag,
-		body,
-	}, nil
+	priority := 0
+	if len(body) >= 2 && body[0] == '!' && body[1] >= '1' && body[1] <= '0'+lowestPriority &&
+		(len(body) == 2 || body[2] == ' ') {
+		priority = int(body[1] - '0')
+		body = strings.TrimPrefix(body[2:], " ")
+	}
+	return commandTag, priority, body
 }
 
 type Activities []OneActivity
 
 // Returns a new Activities struct containing only those
 // in 'this' that have timestamps earlier than that given
-/* Must preserve order */
-func (this Activities) onlyThoseBefore(cutoff time.Time) Activities {
+/* Must be sorted, and preserves order */
+func (this Activities) OnlyThoseBefore(cutoff time.Time) Activities {
 	for i := len(this); i > 0; i-- {
 		if this[i-1].Timestamp.Before(cutoff) {
 			return this[0:i]
@@ -115,6 +191,12 @@ func (this Activities) Sort() {
 	sort.Sort(ByTime(this))
 }
 
+// Sorts in place. Most urgent first, then those with no priority, and
+// earliest first within each priority
+func (this Activities) SortByPriority() {
+	sort.Sort(ByPriority(this))
+}
+
 type ByTime Activities
 
 func (a ByTime) Len() int      { return len(a) }
@@ -128,3 +210,22 @@ func (a ByTime) Less(i, j int) bool {
 	}
 	return a[i].Body < a[j].Body
 }
+
+type ByPriority Activities
+
+func (a ByPriority) Len() int      { return len(a) }
+func (a ByPriority) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a ByPriority) Less(i, j int) bool {
+	if a[i].rank() != a[j].rank() {
+		return a[i].rank() < a[j].rank()
+	}
+	return ByTime(a).Less(i, j)
+}
+
+// rank puts activities with no priority after those with the lowest
+func (this OneActivity) rank() int {
+	if this.Priority == 0 {
+		return lowestPriority + 1
+	}
+	return this.Priority
+}
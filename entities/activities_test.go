@@ -0,0 +1,250 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package entities
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func at(stamp string) time.Time {
+	timestamp, err := time.ParseInLocation("2006-01-02 15:04", stamp, time.UTC)
+	if err != nil {
+		panic(err)
+	}
+	return timestamp
+}
+
+func TestParseOneActivity(t *testing.T) {
+	Zone = time.UTC
+	berlin, _ := LoadZone("Europe/Berlin")
+	tests := []struct {
+		input      string
+		timestamp  time.Time
+		commandTag string
+		priority   int
+		body       string
+		wantErr    bool
+	}{
+		{input: "2014-01-01 12:00 Wash the car", timestamp: at("2014-01-01 12:00"), body: "Wash the car"},
+		{input: "2014-01-01 12:00 ", timestamp: at("2014-01-01 12:00"), body: ""},
+		{input: "2014-01-01 12:00  two spaces", timestamp: at("2014-01-01 12:00"), body: " two spaces"},
+		{input: "2014-01-01 12:00 @rtask:every-n-hours:48 SRS a headline",
+			timestamp: at("2014-01-01 12:00"), commandTag: "every-n-hours:48", body: "SRS a headline"},
+		// a tag with nothing after it is not taken to be a tag
+		{input: "2014-01-01 12:00 @rtask:every-n-hours:48",
+			timestamp: at("2014-01-01 12:00"), body: "@rtask:every-n-hours:48"},
+		{input: "2014-01-01 12:00 @rtask", timestamp: at("2014-01-01 12:00"), body: "@rtask"},
+		{input: "2014-01-01 12:00 Europe/Berlin Wash the car",
+			timestamp: time.Date(2014, 1, 1, 12, 0, 0, 0, berlin), body: "Wash the car"},
+		{input: "2014-01-01 12:00 +02:00 @rtask:after-n-days:1 Wash the car",
+			timestamp: at("2014-01-01 10:00"), commandTag: "after-n-days:1", body: "Wash the car"},
+		{input: "2014-01-01 12:00 UTC Wash the car", timestamp: at("2014-01-01 12:00"), body: "Wash the car"},
+		{input: "2014-01-01 12:00 !1 Renew the passport", timestamp: at("2014-01-01 12:00"), priority: 1, body: "Renew the passport"},
+		{input: "2014-01-01 12:00 UTC @rtask:after-n-days:1 !3 Wash the car",
+			timestamp: at("2014-01-01 12:00"), commandTag: "after-n-days:1", priority: 3, body: "Wash the car"},
+		{input: "2014-01-01 12:00 !2", timestamp: at("2014-01-01 12:00"), priority: 2, body: ""},
+		// only "!1" to "!3" on their own are priorities
+		{input: "2014-01-01 12:00 !4 Wash the car", timestamp: at("2014-01-01 12:00"), body: "!4 Wash the car"},
+		{input: "2014-01-01 12:00 !12 Wash the car", timestamp: at("2014-01-01 12:00"), body: "!12 Wash the car"},
+		{input: "2014-01-01 12:00 Wash the car !1", timestamp: at("2014-01-01 12:00"), body: "Wash the car !1"},
+		// a word with a slash that is not a zone is part of the body
+		{input: "2014-01-01 12:00 Either/or", timestamp: at("2014-01-01 12:00"), body: "Either/or"},
+		{input: "", wantErr: true},
+		{input: "2014-01-01 12:00", wantErr: true},
+		{input: "Wash the car", wantErr: true},
+		{input: "2014-13-01 12:00 Wash the car", wantErr: true},
+		{input: "2014-02-30 12:00 Wash the car", wantErr: true},
+		{input: "2014-01-01 25:00 Wash the car", wantErr: true},
+		{input: "2014-01-01T12:00 Wash the car", wantErr: true},
+		{input: "01-01-2014 12:00 Wash the car", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseOneActivity(test.input)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%q: got %+v, want an error", test.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+			continue
+		}
+		if !got.Timestamp.Equal(test.timestamp) || got.CommandTag != test.commandTag ||
+			got.Priority != test.priority || got.Body != test.body {
+			t.Errorf("%q: got %s %q %d %q, want %s %q %d %q", test.input,
+				got.Timestamp, got.CommandTag, got.Priority, got.Body,
+				test.timestamp, test.commandTag, test.priority, test.body)
+		}
+	}
+}
+
+func TestFullStringParsesBack(t *testing.T) {
+	Zone = time.UTC
+	for _, input := range []string{
+		"2014-01-01 12:00 UTC Wash the car",
+		"2014-07-01 12:00 Australia/Melbourne @rtask:every-n-days:1:from-12:00 Duolingo",
+		"2014-01-01 12:00 +05:30 Call home",
+		"2014-01-01 12:00 UTC !1 Renew the passport",
+		"2014-01-01 12:00 UTC @rtask:after-n-days:1 !2 Water the plants",
+		"2014-01-01 12:00 Europe/Berlin UTC migration checklist",
+	} {
+		activity, err := ParseOneActivity(input)
+		if err != nil {
+			t.Errorf("%q: %s", input, err)
+			continue
+		}
+		if got := activity.FullString(); got != input {
+			t.Errorf("got %q, want %q", got, input)
+		}
+	}
+}
+
+func TestWithTextLeavesZonesInTheBody(t *testing.T) {
+	stamp := at("2014-01-01 09:00")
+	activity := OneActivity{Timestamp: stamp}.WithText("UTC migration checklist")
+	if activity.Body != "UTC migration checklist" || activity.Timestamp != stamp {
+		t.Errorf("got %+v, want the body as typed at the time given", activity)
+	}
+}
+
+func TestWithText(t *testing.T) {
+	original := OneActivity{Id: "aaa111", Timestamp: at("2014-01-01 12:00"), CommandTag: "after-n-days:1", Priority: 2, Body: "Water the plnats"}
+	if got := original.Text(); got != "@rtask:after-n-days:1 !2 Water the plnats" {
+		t.Errorf("got the text %q", got)
+	}
+	tests := []struct {
+		text       string
+		commandTag string
+		priority   int
+		body       string
+	}{
+		{"@rtask:after-n-days:1 !2 Water the plants", "after-n-days:1", 2, "Water the plants"},
+		{"Water the plants", "", 0, "Water the plants"},
+		{"!1 Water the plants", "", 1, "Water the plants"},
+		{"@rtask:every-n-weeks:1 Water the plants", "every-n-weeks:1", 0, "Water the plants"},
+	}
+	for _, test := range tests {
+		edited := original.WithText(test.text)
+		if edited.Id != original.Id || !edited.Timestamp.Equal(original.Timestamp) {
+			t.Errorf("%q: got %s at %s, want the ID and time kept", test.text, edited.Id, edited.Timestamp)
+		}
+		if edited.CommandTag != test.commandTag || edited.Priority != test.priority || edited.Body != test.body {
+			t.Errorf("%q: got %q, %d, %q", test.text, edited.CommandTag, edited.Priority, edited.Body)
+		}
+		if edited.Text() != test.text {
+			t.Errorf("got the text %q back, want %q", edited.Text(), test.text)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	activities := Activities{
+		{Timestamp: at("2014-01-03 12:00"), Body: "third"},
+		{Timestamp: at("2014-01-01 12:00"), Body: "b"},
+		{Timestamp: at("2014-01-02 12:00"), Body: "second"},
+		{Timestamp: at("2014-01-01 12:00"), Body: "a"},
+	}
+	activities.Sort()
+	got := []string{}
+	for _, activity := range activities {
+		got = append(got, activity.Body)
+	}
+	if want := "a b second third"; strings.Join(got, " ") != want {
+		t.Errorf("got %q, want %q", strings.Join(got, " "), want)
+	}
+}
+
+func TestSortByPriority(t *testing.T) {
+	activities := Activities{
+		{Timestamp: at("2014-01-01 12:00"), Body: "none"},
+		{Timestamp: at("2014-01-03 12:00"), Priority: 3, Body: "low"},
+		{Timestamp: at("2014-01-02 12:00"), Priority: 1, Body: "urgent later"},
+		{Timestamp: at("2014-01-01 12:00"), Priority: 1, Body: "urgent"},
+		{Timestamp: at("2014-01-01 12:00"), Priority: 2, Body: "medium"},
+	}
+	activities.SortByPriority()
+	got := []string{}
+	for _, activity := range activities {
+		got = append(got, activity.Body)
+	}
+	if want := "urgent|urgent later|medium|low|none"; strings.Join(got, "|") != want {
+		t.Errorf("got %q, want %q", strings.Join(got, "|"), want)
+	}
+}
+
+func TestByTime(t *testing.T) {
+	tests := []struct {
+		a, b OneActivity
+		less bool
+	}{
+		{OneActivity{Timestamp: at("2014-01-01 12:00")}, OneActivity{Timestamp: at("2014-01-01 12:01")}, true},
+		{OneActivity{Timestamp: at("2014-01-01 12:01")}, OneActivity{Timestamp: at("2014-01-01 12:00")}, false},
+		{OneActivity{Timestamp: at("2014-01-01 12:00"), Body: "a"}, OneActivity{Timestamp: at("2014-01-01 12:00"), Body: "b"}, true},
+		{OneActivity{Timestamp: at("2014-01-01 12:00"), Body: "b"}, OneActivity{Timestamp: at("2014-01-01 12:00"), Body: "a"}, false},
+		{OneActivity{Timestamp: at("2014-01-01 12:00"), Body: "a"}, OneActivity{Timestamp: at("2014-01-01 12:00"), Body: "a"}, false},
+		// the same moment seen from different zones is the same time
+		{OneActivity{Timestamp: at("2014-01-01 12:00"), Body: "a"}, OneActivity{Timestamp: at("2014-01-01 12:00").In(time.FixedZone("+02:00", 7200)), Body: "b"}, true},
+	}
+	for _, test := range tests {
+		if got := (ByTime{test.a, test.b}).Less(0, 1); got != test.less {
+			t.Errorf("%s %q < %s %q: got %v", test.a.Timestamp, test.a.Body, test.b.Timestamp, test.b.Body, got)
+		}
+	}
+}
+
+func TestOnlyThoseBefore(t *testing.T) {
+	activities := Activities{
+		{Timestamp: at("2014-01-01 12:00"), Body: "first"},
+		{Timestamp: at("2014-01-02 12:00"), Body: "second"},
+		{Timestamp: at("2014-01-03 12:00"), Body: "third"},
+	}
+	tests := []struct {
+		cutoff string
+		want   int
+	}{
+		{"2013-12-31 12:00", 0},
+		{"2014-01-01 12:00", 0},
+		{"2014-01-01 12:01", 1},
+		{"2014-01-02 18:00", 2},
+		{"2015-01-01 00:00", 3},
+	}
+	for _, test := range tests {
+		got := activities.OnlyThoseBefore(at(test.cutoff))
+		if len(got) != test.want {
+			t.Errorf("before %s: got %d activities, want %d", test.cutoff, len(got), test.want)
+			continue
+		}
+		for i := range got {
+			if got[i].Body != activities[i].Body {
+				t.Errorf("before %s: got %q at %d, want %q", test.cutoff, got[i].Body, i, activities[i].Body)
+			}
+		}
+	}
+	if got := (Activities{}).OnlyThoseBefore(at("2014-01-01 12:00")); len(got) != 0 {
+		t.Errorf("got %v from no activities", got)
+	}
+}
+
+func TestIndexedString(t *testing.T) {
+	Zone = time.UTC
+	activity := OneActivity{Id: "aaa111", ShortId: "aaa", Timestamp: at("2014-01-01 12:00"), Body: "Wash the car"}
+	if got, want := activity.IndexedString(), "[\033[1maaa\033[0m] 2014-01-01 12:00 Wash the car"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	activity.ShortId = ""
+	activity.CommandTag = "every-n-days:1"
+	if got, want := activity.IndexedString(), "[\033[1maaa111\033[0m]* 2014-01-01 12:00 Wash the car"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	activity.Priority = 2
+	if got, want := activity.IndexedString(), "[\033[1maaa111\033[0m]* 2014-01-01 12:00 !2 Wash the car"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
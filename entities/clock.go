@@ -0,0 +1,28 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package entities
+
+import "time"
+
+// Clock says what time it is. The use cases and the storage ask a Clock
+// rather than the computer, so that they can be asked what things would
+// look like at another time.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the computer's own clock
+type SystemClock struct{}
+
+func (this SystemClock) Now() time.Time { return time.Now() }
+
+// FixedClock always says it is the same time
+type FixedClock struct {
+	Time time.Time
+}
+
+func (this FixedClock) Now() time.Time { return this.Time }
@@ -0,0 +1,59 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package entities
+
+import "time"
+
+// Change is one activity being added to the stream, taken out of it or
+// edited in it
+type Change struct {
+	Command  string
+	Activity OneActivity
+	// Previous is the activity as it was before an edit
+	Previous OneActivity
+}
+
+const (
+	AddCommand = "ADD"
+	// DoneCommand takes out an activity that was done, and CancelCommand
+	// one that never will be
+	DoneCommand   = "DONE"
+	CancelCommand = "CANCEL"
+	// DeleteCommand takes out an activity for some other reason, such as
+	// when it is delayed. Before DONE and CANCEL it was used for both.
+	DeleteCommand = "DELETE"
+	// EditCommand keeps the activity's ID and changes the rest of it
+	EditCommand = "EDIT"
+)
+
+// Operation is the changes made by one command the user gave, named for
+// that command: "new", "done", "cancel", "delay", "reschedule", "repeat" or
+// "edit".
+type Operation struct {
+	Name    string
+	Now     time.Time
+	Changes []Change
+}
+
+// Inverse is the changes that put things back the way they were before
+// this operation
+func (this Operation) Inverse() []Change {
+	output := make([]Change, len(this.Changes))
+	for i, change := range this.Changes {
+		inverse := change
+		switch change.Command {
+		case AddCommand:
+			inverse.Command = DeleteCommand
+		case DoneCommand, CancelCommand, DeleteCommand:
+			inverse.Command = AddCommand
+		case EditCommand:
+			inverse.Activity, inverse.Previous = change.Previous, change.Activity
+		}
+		output[len(this.Changes)-1-i] = inverse
+	}
+	return output
+}
@@ -0,0 +1,215 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package entities
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RepeatRule says when the next occurrence of a repeating activity is due.
+//
+// A fixed-schedule rule ("every") steps forward from the timestamp of the
+// occurrence just done, skipping any occurrences already in the past, so the
+// activity stays on its schedule however late it is done.
+// A completion-relative rule ("after") counts from the moment the occurrence
+// was done.
+//
+// It is stored in the CommandTag of an activity as
+//
+//	every-n-days:1:from-12:00
+//	every-n-hours:48
+//	after-n-weeks:1
+type RepeatRule struct {
+	Fixed bool
+	Count int
+	Unit  string
+	From  string
+}
+
+var repeatUnits = map[string]string{
+	"hour":   "hours",
+	"hours":  "hours",
+	"day":    "days",
+	"days":   "days",
+	"week":   "weeks",
+	"weeks":  "weeks",
+	"month":  "months",
+	"months": "months",
+}
+
+// ParseRepeatRule reads a rule from the CommandTag form described above.
+func ParseRepeatRule(tag string) (RepeatRule, error) {
+	parts := strings.SplitN(tag, ":", 3)
+	if len(parts) < 2 {
+		return RepeatRule{}, fmt.Errorf("Repeat command '%s' has no count", tag)
+	}
+	kind := strings.SplitN(parts[0], "-n-", 2)
+	if len(kind) != 2 {
+		return RepeatRule{}, fmt.Errorf("Repeat command '%s' is not 'every-n-UNIT' or 'after-n-UNIT'", tag)
+	}
+	from := ""
+	if len(parts) == 3 {
+		if !strings.HasPrefix(parts[2], "from-") {
+			return RepeatRule{}, fmt.Errorf("Repeat command '%s' has '%s' where 'from-HH:MM' was expected", tag, parts[2])
+		}
+		from = parts[2][len("from-"):]
+	}
+	return newRepeatRule(kind[0], parts[1], kind[1], from)
+}
+
+// ParseRepeatWords reads a rule written the way a person would type it
+// after 'acts new repeat':
+//
+//	every 1 days from 12:00 Duolingo
+//	after 1 week Water the plants
+//
+// It returns the rule and the words that follow it.
+func ParseRepeatWords(words []string) (RepeatRule, []string, error) {
+	if len(words) < 3 {
+		return RepeatRule{}, words, fmt.Errorf("Expected 'every COUNT UNIT' or 'after COUNT UNIT' but got %v", words)
+	}
+	from := ""
+	rest := words[3:]
+	if len(rest) > 1 && rest[0] == "from" {
+		from = rest[1]
+		rest = rest[2:]
+	}
+	rule, err := newRepeatRule(words[0], words[1], words[2], from)
+	return rule, rest, err
+}
+
+func newRepeatRule(kind, count, unit, from string) (RepeatRule, error) {
+	rule := RepeatRule{From: from}
+	switch kind {
+	case "every":
+		rule.Fixed = true
+	case "after":
+		rule.Fixed = false
+	default:
+		return RepeatRule{}, fmt.Errorf("Repeat kind '%s' not found. Legal kinds are 'every','after'", kind)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 {
+		return RepeatRule{}, fmt.Errorf("Repeat count '%s' is not a positive number", count)
+	}
+	rule.Count = n
+	normalised, ok := repeatUnits[unit]
+	if !ok {
+		return RepeatRule{}, fmt.Errorf("Unit '%s' not found. Legal units are 'month','week','day','hour'", unit)
+	}
+	rule.Unit = normalised
+	if from != "" {
+		if !rule.Fixed {
+			return RepeatRule{}, fmt.Errorf("Only 'every' rules can start 'from' a time")
+		}
+		if _, err := time.Parse("15:04", from); err != nil {
+			return RepeatRule{}, fmt.Errorf("Start time '%s' is not HH:MM", from)
+		}
+	}
+	return rule, nil
+}
+
+// Tag is the CommandTag that ParseRepeatRule reads back into this rule.
+func (this RepeatRule) Tag() string {
+	kind := "after"
+	if this.Fixed {
+		kind = "every"
+	}
+	tag := fmt.Sprintf("%s-n-%s:%d", kind, this.Unit, this.Count)
+	if this.From != "" {
+		tag = fmt.Sprintf("%s:from-%s", tag, this.From)
+	}
+	return tag
+}
+
+// First is the timestamp of the first occurrence of an activity created
+// now with this rule: today at the 'from' time if there is one, otherwise now.
+func (this RepeatRule) First(now time.Time) time.Time {
+	if this.From == "" {
+		return now.Truncate(time.Minute)
+	}
+	return this.atFrom(now)
+}
+
+// Next is the timestamp of the occurrence following the one due at 'due'
+// that was done at 'done'.
+func (this RepeatRule) Next(due, done time.Time) time.Time {
+	if !this.Fixed {
+		return this.step(done).Truncate(time.Minute)
+	}
+	next := this.snap(this.step(due))
+	for !next.After(done) {
+		next = this.snap(this.step(next))
+	}
+	return next
+}
+
+func (this RepeatRule) step(input time.Time) time.Time {
+	switch this.Unit {
+	case "months":
+		return input.AddDate(0, this.Count, 0)
+	case "weeks":
+		return input.AddDate(0, 0, this.Count*7)
+	case "days":
+		return input.AddDate(0, 0, this.Count)
+	}
+	return input.Add(time.Duration(this.Count) * time.Hour)
+}
+
+// snap moves the clock of the input to the 'from' time for rules measured
+// in days or longer, so that delaying one occurrence doesn't shift the rest.
+func (this RepeatRule) snap(input time.Time) time.Time {
+	if this.From == "" || this.Unit == "hours" {
+		return input
+	}
+	return this.atFrom(input)
+}
+
+func (this RepeatRule) atFrom(input time.Time) time.Time {
+	clock, _ := time.Parse("15:04", this.From)
+	return time.Date(input.Year(), input.Month(), input.Day(),
+		clock.Hour(), clock.Minute(), 0, 0, input.Location())
+}
+
+// most occurrences Occurrences will project, so that a rule repeating every
+// hour over a long range can't run away
+const maxOccurrences = 1000
+
+// Occurrences is the activity and, if it repeats, the occurrences that
+// would follow it if each were done when it is due, for those due from now
+// until 'until'. If the activity is already overdue then the next occurrence
+// is worked out as if it were done now. The occurrences that follow have not
+// been stored, so they have no Id; they keep the ShortId of the activity.
+// An activity whose repeat rule cannot be understood does not repeat here.
+func (this OneActivity) Occurrences(now, until time.Time) Activities {
+	output := Activities{}
+	if !this.Timestamp.Before(now) && this.Timestamp.Before(until) {
+		output = append(output, this)
+	}
+	if !this.HasRepeatCommand() {
+		return output
+	}
+	rule, err := ParseRepeatRule(this.CommandTag)
+	if err != nil {
+		return output
+	}
+	done := this.Timestamp
+	if done.Before(now) {
+		done = now
+	}
+	next := this
+	next.Id = ""
+	next.Timestamp = rule.Next(this.Timestamp, done)
+	for next.Timestamp.Before(until) && len(output) < maxOccurrences {
+		output = append(output, next)
+		next.Timestamp = rule.Next(next.Timestamp, next.Timestamp)
+	}
+	return output
+}
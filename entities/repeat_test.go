@@ -0,0 +1,126 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package entities
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRepeatRule(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    RepeatRule
+		wantErr bool
+	}{
+		{tag: "every-n-days:1:from-12:00", want: RepeatRule{Fixed: true, Count: 1, Unit: "days", From: "12:00"}},
+		{tag: "every-n-hours:48", want: RepeatRule{Fixed: true, Count: 48, Unit: "hours"}},
+		{tag: "after-n-weeks:1", want: RepeatRule{Count: 1, Unit: "weeks"}},
+		{tag: "every-n-month:2", want: RepeatRule{Fixed: true, Count: 2, Unit: "months"}},
+		{tag: "every-n-days", wantErr: true},
+		{tag: "every-days:1", wantErr: true},
+		{tag: "sometimes-n-days:1", wantErr: true},
+		{tag: "every-n-days:0", wantErr: true},
+		{tag: "every-n-fortnights:1", wantErr: true},
+		{tag: "every-n-days:1:at-12:00", wantErr: true},
+		{tag: "every-n-days:1:from-noon", wantErr: true},
+		{tag: "after-n-days:1:from-12:00", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseRepeatRule(test.tag)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%q: got %+v, want an error", test.tag, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", test.tag, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%q: got %+v, want %+v", test.tag, got, test.want)
+		}
+		if again, _ := ParseRepeatRule(got.Tag()); again != got {
+			t.Errorf("%q: Tag() %q reads back as %+v", test.tag, got.Tag(), again)
+		}
+	}
+}
+
+func TestParseRepeatWords(t *testing.T) {
+	rule, rest, err := ParseRepeatWords([]string{"every", "1", "days", "from", "12:00", "Duolingo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Tag() != "every-n-days:1:from-12:00" || len(rest) != 1 || rest[0] != "Duolingo" {
+		t.Errorf("got %q and %q", rule.Tag(), rest)
+	}
+	if _, _, err := ParseRepeatWords([]string{"every", "1"}); err == nil {
+		t.Errorf("got no error for too few words")
+	}
+}
+
+func TestRepeatRuleNext(t *testing.T) {
+	tests := []struct {
+		tag  string
+		due  string
+		done string
+		want string
+	}{
+		// fixed rules keep to the schedule however late they are done
+		{"every-n-days:1:from-12:00", "2014-01-01 12:00", "2014-01-01 13:00", "2014-01-02 12:00"},
+		{"every-n-days:1:from-12:00", "2014-01-01 12:00", "2014-01-04 09:00", "2014-01-04 12:00"},
+		{"every-n-days:1:from-12:00", "2014-01-01 15:00", "2014-01-01 16:00", "2014-01-02 12:00"},
+		{"every-n-hours:48", "2014-01-01 12:00", "2014-01-01 13:00", "2014-01-03 12:00"},
+		{"every-n-weeks:1", "2014-01-01 12:00", "2014-01-01 13:00", "2014-01-08 12:00"},
+		{"every-n-months:1", "2014-01-31 12:00", "2014-01-31 13:00", "2014-03-03 12:00"},
+		// completion-relative rules count from when they are done
+		{"after-n-days:2", "2014-01-01 12:00", "2014-01-05 09:30", "2014-01-07 09:30"},
+		{"after-n-hours:3", "2014-01-01 12:00", "2014-01-01 12:00", "2014-01-01 15:00"},
+	}
+	for _, test := range tests {
+		rule, err := ParseRepeatRule(test.tag)
+		if err != nil {
+			t.Errorf("%q: %s", test.tag, err)
+			continue
+		}
+		if got := rule.Next(at(test.due), at(test.done)); !got.Equal(at(test.want)) {
+			t.Errorf("%q due %s done %s: got %s, want %s", test.tag, test.due, test.done, got, test.want)
+		}
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	daily := OneActivity{Id: "aaa111", ShortId: "aaa", Timestamp: at("2014-01-01 09:00"), CommandTag: "every-n-days:1:from-09:00"}
+	tests := []struct {
+		activity OneActivity
+		now      string
+		until    string
+		want     []string
+	}{
+		{daily, "2014-01-01 08:00", "2014-01-03 09:00", []string{"2014-01-01 09:00 aaa111", "2014-01-02 09:00 "}},
+		// overdue: the next one is as if it were done now
+		{daily, "2014-01-05 10:00", "2014-01-07 10:00", []string{"2014-01-06 09:00 ", "2014-01-07 09:00 "}},
+		{OneActivity{Id: "bbb", Timestamp: at("2014-01-01 09:00")}, "2014-01-01 08:00", "2014-01-02 00:00", []string{"2014-01-01 09:00 bbb"}},
+		{OneActivity{Id: "bbb", Timestamp: at("2014-01-01 09:00")}, "2014-01-01 10:00", "2014-01-02 00:00", []string{}},
+		{OneActivity{Id: "ccc", Timestamp: at("2014-01-01 09:00"), CommandTag: "nonsense"}, "2014-01-01 08:00", "2015-01-01 00:00", []string{"2014-01-01 09:00 ccc"}},
+	}
+	for _, test := range tests {
+		got := []string{}
+		for _, occurrence := range test.activity.Occurrences(at(test.now), at(test.until)) {
+			got = append(got, occurrence.Timestamp.Format("2006-01-02 15:04")+" "+occurrence.Id)
+		}
+		if strings.Join(got, "|") != strings.Join(test.want, "|") {
+			t.Errorf("%s from %s until %s: got %q, want %q", test.activity.CommandTag, test.now, test.until, got, test.want)
+		}
+	}
+
+	hourly := OneActivity{Timestamp: at("2014-01-01 09:00"), CommandTag: "every-n-hours:1"}
+	if got := hourly.Occurrences(at("2014-01-01 08:00"), at("2020-01-01 00:00")); len(got) != maxOccurrences {
+		t.Errorf("got %d occurrences, want no more than %d", len(got), maxOccurrences)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package entities
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Projects are the words of the body that start with '+' and then a
+// letter, as in "Write the report +work", without the '+'. A phone number
+// such as "+61 400 000 000" has no projects.
+func (this OneActivity) Projects() []string {
+	return this.tagged("+")
+}
+
+// Contexts are the words of the body that start with '@' and then a
+// letter, as in "Buy milk @shops", without the '@'. A repeat marker
+// "@rtask:" left in the body is not a context.
+func (this OneActivity) Contexts() []string {
+	return this.tagged("@")
+}
+
+func (this OneActivity) tagged(sigil string) []string {
+	output := []string{}
+	for _, word := range strings.Fields(this.Body) {
+		if isTag(word) && strings.HasPrefix(word, sigil) && !strings.HasPrefix(word, "@rtask:") {
+			output = append(output, word[1:])
+		}
+	}
+	return output
+}
+
+// isTag says whether the word is a '+' or '@' followed by a letter
+func isTag(word string) bool {
+	if len(word) < 2 || (word[0] != '+' && word[0] != '@') {
+		return false
+	}
+	first, _ := utf8.DecodeRuneInString(word[1:])
+	return unicode.IsLetter(first)
+}
+
+// Filter picks out activities by their projects and contexts. An activity
+// passes if it has every tag in Include and none in Exclude. Tags are
+// written with their sigil, as "+work" or "@home", and are compared
+// without regard to case.
+type Filter struct {
+	Include []string
+	Exclude []string
+}
+
+// IsFilterWord says whether the word is one that ParseFilter reads:
+// "+work", "@home", "-+work" or "-@home"
+func IsFilterWord(word string) bool {
+	return isTag(strings.TrimPrefix(word, "-"))
+}
+
+// ParseFilter reads a filter from words such as "+work" and "-@home"; a
+// word starting with '-' excludes the tag that follows it
+func ParseFilter(words []string) (Filter, error) {
+	filter := Filter{}
+	for _, word := range words {
+		if !IsFilterWord(word) {
+			return Filter{}, fmt.Errorf("Filter '%s' is not '+project', '@context', '-+project' or '-@context'", word)
+		}
+		if strings.HasPrefix(word, "-") {
+			filter.Exclude = append(filter.Exclude, word[1:])
+		} else {
+			filter.Include = append(filter.Include, word)
+		}
+	}
+	return filter, nil
+}
+
+func (this Filter) Matches(activity OneActivity) bool {
+	tags := map[string]bool{}
+	for _, project := range activity.Projects() {
+		tags["+"+strings.ToLower(project)] = true
+	}
+	for _, context := range activity.Contexts() {
+		tags["@"+strings.ToLower(context)] = true
+	}
+	for _, tag := range this.Include {
+		if !tags[strings.ToLower(tag)] {
+			return false
+		}
+	}
+	for _, tag := range this.Exclude {
+		if tags[strings.ToLower(tag)] {
+			return false
+		}
+	}
+	return true
+}
+
+// Select returns the activities that pass the filter, in the same order
+func (this Filter) Select(activities Activities) Activities {
+	output := Activities{}
+	for _, activity := range activities {
+		if this.Matches(activity) {
+			output = append(output, activity)
+		}
+	}
+	return output
+}
@@ -0,0 +1,85 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package entities
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProjectsAndContexts(t *testing.T) {
+	tests := []struct {
+		body     string
+		projects string
+		contexts string
+	}{
+		{"Write the report +work @desk", "work", "desk"},
+		{"+work +urgent Call Bob @phone @office", "work urgent", "phone office"},
+		{"Email bob@example.com about 1+1", "", ""},
+		{"A lone + and @ are not tags", "", ""},
+		{"call +61 400 000 000 @13:00", "", ""},
+		{"email a@b", "", ""},
+		{"+1 the idea @2pm +Ünterlagen @büro", "Ünterlagen", "büro"},
+		{"@rtask:every-n-days:1 left in the body", "", ""},
+		{"", "", ""},
+	}
+	for _, test := range tests {
+		activity := OneActivity{Body: test.body}
+		if got := strings.Join(activity.Projects(), " "); got != test.projects {
+			t.Errorf("%q: got projects %q, want %q", test.body, got, test.projects)
+		}
+		if got := strings.Join(activity.Contexts(), " "); got != test.contexts {
+			t.Errorf("%q: got contexts %q, want %q", test.body, got, test.contexts)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	activities := Activities{
+		{Body: "Fix the pager +oncall @laptop"},
+		{Body: "Write the report +Work @home"},
+		{Body: "Water the plants @home"},
+		{Body: "Nothing tagged"},
+	}
+	tests := []struct {
+		words   []string
+		want    string
+		wantErr bool
+	}{
+		{words: []string{}, want: "Fix the pager +oncall @laptop|Write the report +Work @home|Water the plants @home|Nothing tagged"},
+		{words: []string{"+work"}, want: "Write the report +Work @home"},
+		{words: []string{"@home"}, want: "Write the report +Work @home|Water the plants @home"},
+		{words: []string{"-@home"}, want: "Fix the pager +oncall @laptop|Nothing tagged"},
+		{words: []string{"@home", "-+work"}, want: "Water the plants @home"},
+		{words: []string{"+oncall", "@home"}, want: ""},
+		{words: []string{"work"}, wantErr: true},
+		{words: []string{"-"}, wantErr: true},
+		{words: []string{"-+"}, wantErr: true},
+		{words: []string{"+61"}, wantErr: true},
+		{words: []string{"-@9am"}, wantErr: true},
+	}
+	for _, test := range tests {
+		filter, err := ParseFilter(test.words)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%q: got %+v, want an error", test.words, filter)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", test.words, err)
+			continue
+		}
+		got := []string{}
+		for _, activity := range filter.Select(activities) {
+			got = append(got, activity.Body)
+		}
+		if strings.Join(got, "|") != test.want {
+			t.Errorf("%q: got %q, want %q", test.words, strings.Join(got, "|"), test.want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package entities
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseWhen reads a moment from the start of the words, the way a person
+// would type it after 'acts new' or 'acts reschedule', and returns it with
+// the words that follow it. It understands
+//
+//	now
+//	2014-01-01 12:00
+//	today, tomorrow, friday or fri, next friday, end of month
+//	in 3 hours, in 2 days, +3h, +2d, +1w, +1m
+//
+// any of which but "now" and a count of hours or minutes may be followed by
+// a time such as "17:30", "9am", "at 9:30pm", "noon" or "midnight", and a
+// time may also be given on its own for today. A zone such as
+// "tz:Europe/Berlin" may follow; otherwise the time is in the configured
+// Zone. The zone is only read with its "tz:", so that text that starts with
+// a word such as "UTC" is left as it is.
+//
+// A day with no time starts at midnight, except that a count of days, weeks
+// or months from now keeps the time it is now. A day of the week is the next
+// one from today, or today itself; "next friday" is never today.
+func ParseWhen(words []string, now time.Time) (time.Time, []string, error) {
+	return parseWhen(words, now, false)
+}
+
+// ParseMoment is ParseWhen for input that is nothing but the moment, as
+// given to 'acts reschedule' or the HTTP server. As no text follows, the
+// zone may also be written without its "tz:".
+func ParseMoment(input string, now time.Time) (time.Time, error) {
+	stamp, rest, err := parseWhen(strings.Fields(input), now, true)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(rest) > 0 {
+		return time.Time{}, fmt.Errorf("Expected nothing after the time in '%s' but got '%s'", input, strings.Join(rest, " "))
+	}
+	return stamp, nil
+}
+
+func parseWhen(words []string, now time.Time, bareZone bool) (time.Time, []string, error) {
+	parser := whenParser{words: words}
+	moment := parser.parse()
+	if !moment.found {
+		return time.Time{}, words, fmt.Errorf("Expected a time such as 'tomorrow 9am', 'fri 17:30', 'in 3 hours' or 'YYYY-MM-DD HH:MM' but got '%s'",
+			strings.Join(words, " "))
+	}
+	loc := Zone
+	if parser.next < len(words) {
+		word := words[parser.next]
+		name, tagged := strings.CutPrefix(word, "tz:")
+		if tagged || (bareZone && parser.next == len(words)-1 && isZoneName(word)) {
+			zone, err := LoadZone(name)
+			if err != nil {
+				return time.Time{}, words, err
+			}
+			loc = zone
+			parser.next++
+		}
+	}
+	return moment.resolve(now, loc), words[parser.next:], nil
+}
+
+// a moment is what the words said, to be worked out against the time now
+type moment struct {
+	found bool
+	// exact is true for counts of hours and minutes, which are the elapsed
+	// while from now
+	exact   bool
+	elapsed time.Duration
+	// day gives the day the moment falls on from the date today; nil is today
+	day func(today time.Time) time.Time
+	// keepClock is true if the moment is at the time of day it is now
+	keepClock bool
+	hasClock  bool
+	hour      int
+	minute    int
+}
+
+func (this moment) resolve(now time.Time, loc *time.Location) time.Time {
+	local := now.In(loc)
+	if this.exact {
+		return local.Add(this.elapsed).Truncate(time.Minute)
+	}
+	day := local
+	if this.day != nil {
+		day = this.day(local)
+	}
+	hour, minute := 0, 0
+	if this.hasClock {
+		hour, minute = this.hour, this.minute
+	} else if this.keepClock {
+		hour, minute = local.Hour(), local.Minute()
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc)
+}
+
+type whenParser struct {
+	words []string
+	next  int
+}
+
+// peek is the word n after the next one, in lower case, or "" past the end
+func (this *whenParser) peek(n int) string {
+	if this.next+n >= len(this.words) {
+		return ""
+	}
+	return strings.ToLower(this.words[this.next+n])
+}
+
+func (this *whenParser) parse() moment {
+	if this.peek(0) == "now" {
+		this.next++
+		return moment{found: true, keepClock: true}
+	}
+	result, ok := this.parseWhile()
+	if ok && result.exact {
+		return result
+	}
+	if !ok {
+		result.day, ok = this.parseDay()
+	}
+	if this.peek(0) == "at" && isClock(this.peek(1)) {
+		this.next++
+	}
+	if hour, minute, isTime := parseClock(this.peek(0)); isTime {
+		this.next++
+		result.hasClock, result.hour, result.minute = true, hour, minute
+		ok = true
+	}
+	result.found = ok
+	return result
+}
+
+var whenUnits = map[string]string{
+	"minute": "minutes", "minutes": "minutes", "min": "minutes", "mins": "minutes",
+	"hour": "hours", "hours": "hours", "h": "hours",
+	"day": "days", "days": "days", "d": "days",
+	"week": "weeks", "weeks": "weeks", "w": "weeks",
+	"month": "months", "months": "months", "m": "months",
+}
+
+var shortWhilePattern = regexp.MustCompile(`^\+(\d+)([a-z]+)$`)
+
+// parseWhile reads "in COUNT UNIT" or "+COUNTU", a while from now
+func (this *whenParser) parseWhile() (moment, bool) {
+	count, unit, width := "", "", 0
+	if match := shortWhilePattern.FindStringSubmatch(this.peek(0)); match != nil {
+		count, unit, width = match[1], match[2], 1
+	} else if this.peek(0) == "in" {
+		count, unit, width = this.peek(1), this.peek(2), 3
+	}
+	n, err := strconv.Atoi(count)
+	normalised, ok := whenUnits[unit]
+	if err != nil || !ok {
+		return moment{}, false
+	}
+	this.next += width
+	switch normalised {
+	case "minutes":
+		return moment{found: true, exact: true, elapsed: time.Duration(n) * time.Minute}, true
+	case "hours":
+		return moment{found: true, exact: true, elapsed: time.Duration(n) * time.Hour}, true
+	case "weeks":
+		n *= 7
+		fallthrough
+	case "days":
+		return moment{keepClock: true, day: func(today time.Time) time.Time { return today.AddDate(0, 0, n) }}, true
+	}
+	return moment{keepClock: true, day: func(today time.Time) time.Time { return today.AddDate(0, n, 0) }}, true
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// parseDay reads the words naming a day, and returns how to find it from today
+func (this *whenParser) parseDay() (func(time.Time) time.Time, bool) {
+	word := this.peek(0)
+	if date, err := time.Parse("2006-01-02", word); err == nil {
+		this.next++
+		return func(time.Time) time.Time { return date }, true
+	}
+	switch word {
+	case "today":
+		this.next++
+		return nil, true
+	case "tomorrow":
+		this.next++
+		return func(today time.Time) time.Time { return today.AddDate(0, 0, 1) }, true
+	case "end":
+		if this.peek(1) == "of" && this.peek(2) == "month" {
+			this.next += 3
+			return func(today time.Time) time.Time {
+				return time.Date(today.Year(), today.Month()+1, 0, 0, 0, 0, 0, today.Location())
+			}, true
+		}
+	case "next":
+		if weekday, ok := weekdays[this.peek(1)]; ok {
+			this.next += 2
+			return func(today time.Time) time.Time { return nextWeekday(today.AddDate(0, 0, 1), weekday) }, true
+		}
+	}
+	if weekday, ok := weekdays[word]; ok {
+		this.next++
+		return func(today time.Time) time.Time { return nextWeekday(today, weekday) }, true
+	}
+	return nil, false
+}
+
+// nextWeekday is the first day from the given one, itself included, that
+// falls on the weekday
+func nextWeekday(from time.Time, weekday time.Weekday) time.Time {
+	return from.AddDate(0, 0, (int(weekday)-int(from.Weekday())+7)%7)
+}
+
+var clockPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+
+func isClock(word string) bool {
+	_, _, ok := parseClock(word)
+	return ok
+}
+
+// parseClock reads "17:30", "9am", "9:30pm", "noon" or "midnight". A bare
+// number such as "9" is not a time, so that it can start the text instead.
+func parseClock(word string) (int, int, bool) {
+	switch word {
+	case "noon":
+		return 12, 0, true
+	case "midnight":
+		return 0, 0, true
+	}
+	match := clockPattern.FindStringSubmatch(word)
+	if match == nil || (match[2] == "" && match[3] == "") {
+		return 0, 0, false
+	}
+	hour, _ := strconv.Atoi(match[1])
+	minute, _ := strconv.Atoi(match[2])
+	if match[3] != "" {
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		hour = hour % 12
+		if match[3] == "pm" {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return 0, 0, false
+	}
+	return hour, minute, true
+}
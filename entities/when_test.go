@@ -0,0 +1,94 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package entities
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWhen(t *testing.T) {
+	Zone = time.UTC
+	// a Wednesday
+	now := at("2014-01-01 10:20")
+	tests := []struct {
+		input string
+		want  string
+		rest  string
+	}{
+		{"now Wash the car", "2014-01-01 10:20 UTC", "Wash the car"},
+		{"2014-02-03 12:00 Wash the car", "2014-02-03 12:00 UTC", "Wash the car"},
+		{"2014-02-03 Wash the car", "2014-02-03 00:00 UTC", "Wash the car"},
+		{"17:30 Wash the car", "2014-01-01 17:30 UTC", "Wash the car"},
+		{"9am Wash the car", "2014-01-01 09:00 UTC", "Wash the car"},
+		{"today noon", "2014-01-01 12:00 UTC", ""},
+		{"tomorrow 9am Wash the car", "2014-01-02 09:00 UTC", "Wash the car"},
+		{"Tomorrow at 9:30pm Wash the car", "2014-01-02 21:30 UTC", "Wash the car"},
+		{"tomorrow Wash the car", "2014-01-02 00:00 UTC", "Wash the car"},
+		{"tomorrow 12am", "2014-01-02 00:00 UTC", ""},
+		{"tomorrow 12pm", "2014-01-02 12:00 UTC", ""},
+		{"fri 17:30 Drinks", "2014-01-03 17:30 UTC", "Drinks"},
+		{"wednesday 9am", "2014-01-01 09:00 UTC", ""},
+		{"next wednesday 9am", "2014-01-08 09:00 UTC", ""},
+		{"next monday", "2014-01-06 00:00 UTC", ""},
+		{"end of month Pay rent", "2014-01-31 00:00 UTC", "Pay rent"},
+		{"in 3 hours Check the oven", "2014-01-01 13:20 UTC", "Check the oven"},
+		{"in 90 minutes", "2014-01-01 11:50 UTC", ""},
+		{"in 2 days", "2014-01-03 10:20 UTC", ""},
+		{"+2d Water the plants", "2014-01-03 10:20 UTC", "Water the plants"},
+		{"+2d 8am", "2014-01-03 08:00 UTC", ""},
+		{"+1w", "2014-01-08 10:20 UTC", ""},
+		{"+1m", "2014-02-01 10:20 UTC", ""},
+		{"+3h", "2014-01-01 13:20 UTC", ""},
+		{"+0h", "2014-01-01 10:20 UTC", ""},
+		{"tomorrow 9am tz:Europe/Berlin Call home", "2014-01-02 09:00 Europe/Berlin", "Call home"},
+		{"2014-01-01 12:00 tz:+05:30", "2014-01-01 12:00 +05:30", ""},
+		// a zone is only read with its tz:, as text may start with one
+		{"tomorrow 9am UTC migration checklist", "2014-01-02 09:00 UTC", "UTC migration checklist"},
+		{"tomorrow 9am Europe/Berlin trip", "2014-01-02 09:00 UTC", "Europe/Berlin trip"},
+		// words that only look like the start of a time are left to the text
+		{"9am 3 apples", "2014-01-01 09:00 UTC", "3 apples"},
+		{"tomorrow at the shops", "2014-01-02 00:00 UTC", "at the shops"},
+		{"now 9am", "2014-01-01 10:20 UTC", "9am"},
+	}
+	for _, test := range tests {
+		got, rest, err := ParseWhen(strings.Fields(test.input), now)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+			continue
+		}
+		if stamp := got.Format("2006-01-02 15:04 ") + zoneName(got); stamp != test.want {
+			t.Errorf("%q: got %s, want %s", test.input, stamp, test.want)
+		}
+		if strings.Join(rest, " ") != test.rest {
+			t.Errorf("%q: left %q, want %q", test.input, strings.Join(rest, " "), test.rest)
+		}
+	}
+}
+
+func TestParseWhenErrors(t *testing.T) {
+	for _, input := range []string{"", "Wash the car", "9 Wash the car", "13pm", "25:00", "in three hours", "+2y", "next week", "9am tz:Mars/Olympus Wash the car"} {
+		if got, _, err := ParseWhen(strings.Fields(input), at("2014-01-01 10:20")); err == nil {
+			t.Errorf("%q: got %s, want an error", input, got)
+		}
+	}
+}
+
+func TestParseMoment(t *testing.T) {
+	Zone = time.UTC
+	now := at("2014-01-01 10:20")
+	if got, err := ParseMoment("fri 17:30", now); err != nil || !got.Equal(at("2014-01-03 17:30")) {
+		t.Errorf("got %s %v, want 2014-01-03 17:30", got, err)
+	}
+	if got, err := ParseMoment("fri 17:30 Europe/Berlin", now); err != nil || !got.Equal(time.Date(2014, 1, 3, 16, 30, 0, 0, time.UTC)) {
+		t.Errorf("got %s %v, want 17:30 in Berlin", got, err)
+	}
+	if got, err := ParseMoment("fri 17:30 Drinks", now); err == nil {
+		t.Errorf("got %s, want an error for the words left over", got)
+	}
+}
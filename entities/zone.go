@@ -0,0 +1,131 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package entities
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Zone is where a time typed without a zone is taken to be, and where
+// times are shown. The front ends set it with ConfigureZone.
+var Zone = time.Local
+
+// ConfigureZone sets Zone from a name such as those LoadZone understands.
+// With no name it uses the zone of this computer, by name if that can be found.
+func ConfigureZone(name string) error {
+	if name == "" {
+		name = localZoneName()
+	}
+	loc, err := LoadZone(name)
+	if err != nil {
+		return err
+	}
+	Zone = loc
+	return nil
+}
+
+var offsetPattern = regexp.MustCompile(`^([+-])(\d{2}):?(\d{2})$`)
+
+var zoneCache = struct {
+	sync.Mutex
+	zones map[string]*time.Location
+}{zones: map[string]*time.Location{}}
+
+// LoadZone understands "Local", "UTC", offsets such as "+02:00" or "-0530"
+// and names from the time zone database such as "Europe/Berlin".
+func LoadZone(name string) (*time.Location, error) {
+	if name == "Local" {
+		return time.Local, nil
+	}
+	if match := offsetPattern.FindStringSubmatch(name); match != nil {
+		offset, err := time.Parse("-07:00", fmt.Sprintf("%s%s:%s", match[1], match[2], match[3]))
+		if err != nil {
+			return nil, fmt.Errorf("Time zone offset '%s' is not ±HH:MM", name)
+		}
+		_, seconds := offset.Zone()
+		return time.FixedZone(offsetName(seconds), seconds), nil
+	}
+
+	zoneCache.Lock()
+	defer zoneCache.Unlock()
+	if loc, ok := zoneCache.zones[name]; ok {
+		return loc, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("Time zone '%s' not found: %s", name, err)
+	}
+	zoneCache.zones[name] = loc
+	return loc, nil
+}
+
+// ParseTimestamp reads "YYYY-MM-DD HH:MM" in the configured Zone, or
+// "YYYY-MM-DD HH:MM ZONE" in the named zone
+func ParseTimestamp(input string) (time.Time, error) {
+	loc := Zone
+	if len(input) > 17 {
+		zone, err := LoadZone(input[17:len(input)])
+		if err != nil {
+			return time.Time{}, err
+		}
+		loc = zone
+		input = input[0:16]
+	}
+	return time.ParseInLocation("2006-01-02 15:04", input, loc)
+}
+
+// isZoneName says whether a word written after a timestamp is a zone
+func isZoneName(word string) bool {
+	if word == "UTC" || offsetPattern.MatchString(word) {
+		return true
+	}
+	if !strings.Contains(word, "/") {
+		return false
+	}
+	_, err := LoadZone(word)
+	return err == nil
+}
+
+// zoneName is how the zone of the given time is written after it: the name
+// from the time zone database if it has one, so that repeating activities
+// follow daylight saving, otherwise the offset from UTC.
+func zoneName(stamp time.Time) string {
+	name := stamp.Location().String()
+	if name == "UTC" || strings.Contains(name, "/") {
+		return name
+	}
+	_, seconds := stamp.Zone()
+	return offsetName(seconds)
+}
+
+func offsetName(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d:%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+// localZoneName finds the name of this computer's zone from $TZ or the
+// /etc/localtime link, or falls back on "Local"
+func localZoneName() string {
+	if tz := os.Getenv("TZ"); tz != "" {
+		return strings.TrimPrefix(tz, ":")
+	}
+	if link, err := os.Readlink("/etc/localtime"); err == nil {
+		if i := strings.Index(link, "zoneinfo/"); i >= 0 {
+			return link[i+len("zoneinfo/"):]
+		}
+	}
+	return "Local"
+}
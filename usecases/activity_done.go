@@ -9,42 +9,69 @@ package usecases
 import (
 	"fmt"
 
-	"bytes"
 	"github.com/Fepelus/ActivityStream/entities"
 )
 
+type CommandFinder interface {
+	FindActivity(id string) (entities.Activities, error)
+}
+
 type CommandDeleter interface {
-	FindActivity(id string) entities.Activities
+	CommandFinder
 	Delete(activity entities.OneActivity) error
 }
 
+type CommandCompleter interface {
+	CommandFinder
+	CommandGetter
+	Done(activity entities.OneActivity) error
+	Repeat(done, next entities.OneActivity) (string, error)
+}
+
 /*
  * Basic flow :-
  * The user passes the ID.
  * The usecase fetches the single matching activity
- * The usecase gives the 'done' command to the deleter with this activity
+ * The usecase gives the 'done' command to the completer with this activity
+ * If the activity has a repeat command then instead the usecase works out
+ *   when the next occurrence is due after the time the clock says, sends
+ *   both to the completer to record the activity as done along with its
+ *   next occurrence, and returns the new ID
  *
  * Alternative flows :-
  *  if the ID matches no activities then return a message to the user
  *  if the ID matches several activities then return them to the user and request a new ID
+ *  if the storage cannot be read or written then return its error
+ *  if the repeat command cannot be understood then the activity is still
+ *    done but no next occurrence is stored, and a message is returned to the user
+ *  if the activity was done, cancelled or delayed elsewhere since it was
+ *    found then return the storage's error
  */
-func MarkActivityAsDone(id string, deleter CommandDeleter) error {
-	activities := deleter.FindActivity(id)
+func MarkActivityAsDone(id string, completer CommandCompleter, clock entities.Clock) (string, error) {
+	thisActivity, err := findOnlyActivity(id, completer)
+	if err != nil {
+		return "", err
+	}
 
-	if len(activities) == 0 {
-		return fmt.Errorf("No activities found with index %s\n", id)
+	if !thisActivity.HasRepeatCommand() {
+		return "", completer.Done(thisActivity)
 	}
-	if len(activities) > 1 {
-		var buffer bytes.Buffer
-		buffer.WriteString("Ambiguous ID matches:\n")
-		for i := 0; i < len(activities); i++ {
-			buffer.WriteString(activities[i].IndexedString())
-			buffer.WriteString("\n")
+
+	rule, err := entities.ParseRepeatRule(thisActivity.CommandTag)
+	if err != nil {
+		if err := completer.Done(thisActivity); err != nil {
+			return "", err
 		}
-		buffer.WriteString("\nNothing has been deleted. You may try again.\n")
-		return fmt.Errorf(buffer.String())
+		return "", fmt.Errorf("Done, but it will not repeat: %s", err)
 	}
-
-	deleter.Delete(activities[0])
-	return nil
+	next := thisActivity
+	next.Id = ""
+	next.ShortId = ""
+	next.Predecessor = ""
+	next.Timestamp = rule.Next(thisActivity.Timestamp, clock.Now())
+	newHashId, err := completer.Repeat(thisActivity, next)
+	if err != nil {
+		return "", err
+	}
+	return shortIdAmongLive(newHashId, completer), nil
 }
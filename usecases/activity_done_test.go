@@ -0,0 +1,121 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMarkActivityAsDone(t *testing.T) {
+	store := newFakeStore(
+		activity("aaa111", "2014-01-01 12:00", "Wash the car"),
+		activity("bbb222", "2014-01-02 12:00", "Feed the cat"),
+	)
+	hash, err := MarkActivityAsDone("aaa", store, clockAt("2014-01-03 12:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != "" {
+		t.Errorf("got ID %q for an activity that does not repeat", hash)
+	}
+	if len(store.done) != 1 || store.done[0].Id != "aaa111" {
+		t.Errorf("done %v, want aaa111", store.done)
+	}
+	if len(store.activities) != 1 || store.activities[0].Id != "bbb222" {
+		t.Errorf("left %v, want bbb222", store.activities)
+	}
+}
+
+func TestMarkActivityAsDoneRepeats(t *testing.T) {
+	due := activity("aaa111", "2014-01-01 12:00", "Duolingo")
+	due.CommandTag = "every-n-days:1:from-12:00"
+	store := newFakeStore(due, activity("bbb222", "2014-01-02 12:00", "Feed the cat"))
+
+	hash, err := MarkActivityAsDone("aaa", store, clockAt("2014-01-03 13:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(store.done) != 1 || store.done[0].Id != "aaa111" || len(store.added) != 1 {
+		t.Fatalf("done %v and added %v, want aaa111 done and its next occurrence", store.done, store.added)
+	}
+	next := store.added[0]
+	if !strings.HasPrefix(next.Id, hash) || len(hash) < minIdLength {
+		t.Errorf("returned %q, want a prefix of %q", hash, next.Id)
+	}
+	if next.CommandTag != due.CommandTag || next.Body != due.Body {
+		t.Errorf("next occurrence is %+v, want the same tag and body as %+v", next, due)
+	}
+	if want := activity("", "2014-01-04 12:00", "").Timestamp; !next.Timestamp.Equal(want) {
+		t.Errorf("next occurrence is due %s, want %s", next.Timestamp, want)
+	}
+}
+
+func TestMarkActivityAsDoneBadRepeat(t *testing.T) {
+	due := activity("aaa111", "2014-01-01 12:00", "Duolingo")
+	due.CommandTag = "every-n-fortnights:1"
+	store := newFakeStore(due)
+
+	_, err := MarkActivityAsDone("aaa", store, clockAt("2014-01-03 13:00"))
+	if err == nil || !strings.HasPrefix(err.Error(), "Done, but it will not repeat") {
+		t.Errorf("got error %v, want one saying it will not repeat", err)
+	}
+	if len(store.done) != 1 || len(store.activities) != 0 || len(store.added) != 0 {
+		t.Errorf("left %v and added %v, want the activity done and nothing added", store.activities, store.added)
+	}
+}
+
+func TestMarkActivityAsDoneAlternativeFlows(t *testing.T) {
+	broken := errors.New("disk on fire")
+	tests := []struct {
+		name  string
+		id    string
+		err   error
+		check func(t *testing.T, err error)
+	}{
+		{"not found", "ccc", nil, func(t *testing.T, err error) {
+			var notFound *NotFoundError
+			if !errors.As(err, &notFound) || notFound.Id != "ccc" {
+				t.Errorf("got %v, want a NotFoundError for ccc", err)
+			}
+		}},
+		{"ambiguous", "aa", nil, func(t *testing.T, err error) {
+			var ambiguous *AmbiguousIdError
+			if !errors.As(err, &ambiguous) {
+				t.Fatalf("got %v, want an AmbiguousIdError", err)
+			}
+			if len(ambiguous.Candidates) != 2 {
+				t.Errorf("got %d candidates, want 2", len(ambiguous.Candidates))
+			}
+			for _, candidate := range ambiguous.Candidates {
+				if candidate.ShortId != candidate.Id[0:4] {
+					t.Errorf("candidate %s has short ID %q, want %q", candidate.Id, candidate.ShortId, candidate.Id[0:4])
+				}
+			}
+		}},
+		{"storage fails", "aaa1", broken, func(t *testing.T, err error) {
+			if err != broken {
+				t.Errorf("got %v, want the storage's error", err)
+			}
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newFakeStore(
+				activity("aaa111", "2014-01-01 12:00", "Wash the car"),
+				activity("aaa222", "2014-01-02 12:00", "Feed the cat"),
+			)
+			store.err = test.err
+			_, err := MarkActivityAsDone(test.id, store, clockAt("2014-01-03 13:00"))
+			test.check(t, err)
+			if len(store.done) != 0 || len(store.added) != 0 {
+				t.Errorf("done %v and added %v, want nothing changed", store.done, store.added)
+			}
+		})
+	}
+}
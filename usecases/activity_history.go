@@ -0,0 +1,193 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+// Event is something that happened to an activity: it was "created",
+// "delayed", "rescheduled", "edited", "done", "cancelled" or "deleted"
+type Event struct {
+	At   time.Time
+	What string
+	// Activity is the activity as the event left it, with a ShortId that
+	// is unique among every activity there has been
+	Activity entities.OneActivity
+}
+
+// movedAs is what an activity is said to have been when it was replaced by
+// an operation of that name
+var movedAs = map[string]string{"delay": "delayed", "reschedule": "rescheduled"}
+
+//
+// Basic flow :-
+// The user passes the ID of any activity that was delayed or rescheduled
+//   from another or into another, or of none.
+// The usecase fetches the history of the operations that have not been undone
+// It finds the activity with that ID, whether or not it is still to be done
+// It follows the activity back to the one it was first created as, and on
+//   through each delay and reschedule to the one it is now
+// And returns what happened to it, oldest first
+//
+// Alternative flows :-
+//  if the ID matches no activities then return a message to the user
+//  if the ID matches activities that are not the same one delayed then
+//    return them to the user and request a new ID
+//  if the storage cannot be read then return its error
+//  an activity delayed or rescheduled before the one it came from was
+//    recorded is followed through the operation that replaced it
+//
+func ActivityHistory(id string, historian CommandHistorian) ([]Event, error) {
+	history, err := historian.History()
+	if err != nil {
+		return nil, err
+	}
+
+	predecessors := map[string]string{}
+	successors := map[string]string{}
+	latest := map[string]entities.OneActivity{}
+	order := []string{}
+	for _, operation := range history {
+		removed := []string{}
+		for _, change := range operation.Changes {
+			if _, seen := latest[change.Activity.Id]; !seen {
+				order = append(order, change.Activity.Id)
+			}
+			latest[change.Activity.Id] = change.Activity
+			if change.Command != entities.AddCommand && change.Command != entities.EditCommand {
+				removed = append(removed, change.Activity.Id)
+			}
+		}
+		for _, change := range operation.Changes {
+			if change.Command != entities.AddCommand {
+				continue
+			}
+			from := change.Activity.Predecessor
+			if _, moved := movedAs[operation.Name]; from == "" && moved && len(removed) == 1 {
+				from = removed[0]
+			}
+			if from != "" {
+				predecessors[change.Activity.Id] = from
+				successors[from] = change.Activity.Id
+			}
+		}
+	}
+
+	first := func(id string) string {
+		seen := map[string]bool{}
+		for predecessors[id] != "" && !seen[id] {
+			seen[id] = true
+			id = predecessors[id]
+		}
+		return id
+	}
+	matches := map[string]string{}
+	for _, each := range order {
+		if strings.HasPrefix(each, id) {
+			matches[first(each)] = each
+		}
+	}
+	if len(matches) == 0 {
+		return nil, &NotFoundError{id}
+	}
+	all := entities.Activities{}
+	for _, each := range order {
+		all = append(all, latest[each])
+	}
+	length := uniqueIdLength(all)
+	if len(matches) > 1 {
+		candidates := entities.Activities{}
+		for _, each := range order {
+			if matched, ok := matches[first(each)]; ok && matched == each {
+				candidates = append(candidates, latest[each])
+			}
+		}
+		shortenIds(candidates)
+		return nil, &AmbiguousIdError{id, candidates}
+	}
+
+	chain := map[string]bool{}
+	for start := range matches {
+		for each := start; each != "" && !chain[each]; each = successors[each] {
+			chain[each] = true
+		}
+	}
+	events := []Event{}
+	for _, operation := range history {
+		moved := false
+		for _, change := range operation.Changes {
+			if change.Command == entities.AddCommand && chain[change.Activity.Id] && chain[predecessors[change.Activity.Id]] {
+				moved = true
+			}
+		}
+		for _, change := range operation.Changes {
+			if !chain[change.Activity.Id] {
+				continue
+			}
+			activity := change.Activity
+			activity.ShortId = prefix(activity.Id, length)
+			what := ""
+			switch change.Command {
+			case entities.AddCommand:
+				what = "created"
+				if moved {
+					what = movedAs[operation.Name]
+					if what == "" {
+						what = operation.Name
+					}
+				}
+			case entities.EditCommand:
+				what = "edited"
+			default:
+				if moved {
+					continue
+				}
+				what = outcomeOf(change.Command, operation.Name)
+				if what == "" {
+					what = "deleted"
+				}
+			}
+			events = append(events, Event{At: operation.Now, What: what, Activity: activity})
+		}
+	}
+	return events, nil
+}
+
+// GetHistory is the ActivityHistory as lines of text: what happened to it
+// in short, such as "created, delayed ×3, rescheduled, done", then when
+// each thing happened and what the activity was afterwards
+func GetHistory(id string, historian CommandHistorian) ([]string, error) {
+	events, err := ActivityHistory(id, historian)
+	if err != nil {
+		return nil, err
+	}
+	summary := []string{}
+	repeats := 0
+	for i, event := range events {
+		repeats++
+		if i+1 < len(events) && events[i+1].What == event.What {
+			continue
+		}
+		if repeats > 1 {
+			summary = append(summary, fmt.Sprintf("%s ×%d", event.What, repeats))
+		} else {
+			summary = append(summary, event.What)
+		}
+		repeats = 0
+	}
+	output := []string{strings.Join(summary, ", ")}
+	for _, event := range events {
+		output = append(output, fmt.Sprintf("  %s %-11s %s",
+			event.At.In(entities.Zone).Format("2006-01-02 15:04"), event.What, statsLine(event.Activity)))
+	}
+	return output, nil
+}
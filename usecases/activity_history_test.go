@@ -0,0 +1,106 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+// historyStore has a car delayed twice, then rescheduled by a version that
+// did not record where it came from, then edited and done, and a cat
+func historyStore() *fakeStore {
+	car := activity("aaa111", "2014-01-05 09:00", "Wash the car")
+	delayed := activity("bbb222", "2014-01-06 09:00", "Wash the car")
+	delayed.Predecessor = car.Id
+	again := activity("ccc333", "2014-01-07 09:00", "Wash the car")
+	again.Predecessor = delayed.Id
+	rescheduled := activity("eee555", "2014-01-08 09:00", "Wash the car")
+	edited := rescheduled.WithText("!1 Wash the car")
+	cat := activity("aaa999", "2014-01-05 10:00", "Feed the cat")
+
+	store := newFakeStore(cat)
+	store.operations = []entities.Operation{
+		operation("new", "2014-01-04 12:00", change(entities.AddCommand, car)),
+		operation("new", "2014-01-04 12:05", change(entities.AddCommand, cat)),
+		operation("delay", "2014-01-05 10:00", change(entities.DeleteCommand, car), change(entities.AddCommand, delayed)),
+		operation("delay", "2014-01-06 10:00", change(entities.DeleteCommand, delayed), change(entities.AddCommand, again)),
+		operation("reschedule", "2014-01-07 10:00", change(entities.DeleteCommand, again), change(entities.AddCommand, rescheduled)),
+		operation("edit", "2014-01-07 11:00", entities.Change{Command: entities.EditCommand, Activity: edited, Previous: rescheduled}),
+		operation("done", "2014-01-08 10:00", change(entities.DoneCommand, edited)),
+	}
+	return store
+}
+
+func TestActivityHistoryFollowsTheChainFromAnyId(t *testing.T) {
+	entities.Zone = time.UTC
+	for _, id := range []string{"aaa1", "bbb", "ccc333", "eee"} {
+		events, err := ActivityHistory(id, historyStore())
+		if err != nil {
+			t.Errorf("%s: %s", id, err)
+			continue
+		}
+		got := []string{}
+		for _, event := range events {
+			got = append(got, event.At.Format("01-02 15:04")+" "+event.What+" "+event.Activity.ShortId)
+		}
+		want := "01-04 12:00 created aaa1|01-05 10:00 delayed bbb2|01-06 10:00 delayed ccc3|" +
+			"01-07 10:00 rescheduled eee5|01-07 11:00 edited eee5|01-08 10:00 done eee5"
+		if strings.Join(got, "|") != want {
+			t.Errorf("%s: got %q, want %q", id, strings.Join(got, "|"), want)
+		}
+		if last := events[len(events)-1].Activity; last.Priority != 1 {
+			t.Errorf("%s: got %+v last, want the car as it was edited", id, last)
+		}
+	}
+}
+
+func TestActivityHistoryAlternativeFlows(t *testing.T) {
+	events, err := ActivityHistory("aaa9", historyStore())
+	if err != nil || len(events) != 1 || events[0].What != "created" {
+		t.Errorf("got %+v, %v, want only the cat created", events, err)
+	}
+
+	var ambiguous *AmbiguousIdError
+	if _, err := ActivityHistory("aaa", historyStore()); !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+		t.Errorf("got %v, want the car and the cat to choose from", err)
+	}
+	var notFound *NotFoundError
+	if _, err := ActivityHistory("fff", historyStore()); !errors.As(err, &notFound) {
+		t.Errorf("got %v, want a NotFoundError", err)
+	}
+	broken := errors.New("disk on fire")
+	store := historyStore()
+	store.err = broken
+	if _, err := ActivityHistory("aaa1", store); err != broken {
+		t.Errorf("got %v, want the storage's error", err)
+	}
+}
+
+func TestGetHistory(t *testing.T) {
+	entities.Zone = time.UTC
+	got, err := GetHistory("ccc", historyStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"created, delayed ×2, rescheduled, edited, done",
+		"  2014-01-04 12:00 created     [\033[1maaa1\033[0m] 2014-01-05 09:00 Wash the car",
+		"  2014-01-05 10:00 delayed     [\033[1mbbb2\033[0m] 2014-01-06 09:00 Wash the car",
+		"  2014-01-06 10:00 delayed     [\033[1mccc3\033[0m] 2014-01-07 09:00 Wash the car",
+		"  2014-01-07 10:00 rescheduled [\033[1meee5\033[0m] 2014-01-08 09:00 Wash the car",
+		"  2014-01-07 11:00 edited      [\033[1meee5\033[0m] 2014-01-08 09:00 !1 Wash the car",
+		"  2014-01-08 10:00 done        [\033[1meee5\033[0m] 2014-01-08 09:00 !1 Wash the car",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
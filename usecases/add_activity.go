@@ -9,11 +9,24 @@ package usecases
 import "github.com/Fepelus/ActivityStream/entities"
 
 type CommandAdder interface {
-	AddNew(entities.OneActivity) string
+	CommandGetter
+	AddNew(entities.OneActivity) (string, error)
 }
 
 // AddItem saves the given OneActivity in the datastorage passed as adder
-// it returns a string that represents the ID of the new item in storage.
-func AddItem(cmd entities.OneActivity, adder CommandAdder) string {
-	return adder.AddNew(cmd)
+// it returns a string that represents the ID of the new item in storage,
+// just long enough to tell it apart from the other current items.
+// An activity with a repeat command that cannot be understood is not saved,
+// so that it is not found out only when it is done.
+func AddItem(cmd entities.OneActivity, adder CommandAdder) (string, error) {
+	if cmd.HasRepeatCommand() {
+		if _, err := entities.ParseRepeatRule(cmd.CommandTag); err != nil {
+			return "", err
+		}
+	}
+	id, err := adder.AddNew(cmd)
+	if err != nil {
+		return "", err
+	}
+	return shortIdAmongLive(id, adder), nil
 }
@@ -0,0 +1,69 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAddItem(t *testing.T) {
+	store := newFakeStore(activity("aaa111", "2014-01-01 12:00", "Wash the car"))
+	hash, err := AddItem(activity("", "2014-01-02 12:00", "Feed the cat"), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(store.added) != 1 {
+		t.Fatalf("added %v, want one activity", store.added)
+	}
+	if want := store.added[0].Id[0:minIdLength]; hash != want {
+		t.Errorf("returned %q, want %q", hash, want)
+	}
+}
+
+func TestAddItemShortIdTellsApart(t *testing.T) {
+	added := activity("", "2014-01-02 12:00", "Feed the cat")
+	id := fakeId(added)
+	store := newFakeStore(activity(id[0:5]+"0000", "2014-01-01 12:00", "Wash the car"))
+	hash, err := AddItem(added, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := id[0:6]; hash != want {
+		t.Errorf("returned %q, want %q", hash, want)
+	}
+}
+
+func TestAddItemStorageFails(t *testing.T) {
+	store := newFakeStore()
+	broken := errors.New("disk on fire")
+	store.err = broken
+	if _, err := AddItem(activity("", "2014-01-02 12:00", "Feed the cat"), store); err != broken {
+		t.Errorf("got %v, want the storage's error", err)
+	}
+}
+
+func TestAddItemRefusesABadRepeatCommand(t *testing.T) {
+	for _, tag := range []string{"every-2-days", "every-n-days", "every-n-fortnights:1", "bogus"} {
+		store := newFakeStore()
+		added := activity("", "2014-01-02 12:00", "Feed the cat")
+		added.CommandTag = tag
+		if _, err := AddItem(added, store); err == nil {
+			t.Errorf("%q: got no error", tag)
+		}
+		if len(store.added) != 0 {
+			t.Errorf("%q: added %v, want nothing", tag, store.added)
+		}
+	}
+
+	store := newFakeStore()
+	added := activity("", "2014-01-02 12:00", "Feed the cat")
+	added.CommandTag = "every-n-days:2"
+	if _, err := AddItem(added, store); err != nil || len(store.added) != 1 {
+		t.Errorf("got %v adding %v, want a good repeat command added", err, store.added)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+//
+// Basic flow :-
+// The user passes how far ahead to look, as a count and a unit, and a
+//   filter of projects and contexts.
+// The usecase fetches all the activities that pass the filter
+// It projects each repeating activity forward from its repeat rule
+// And returns those due between now and then, earliest first, with the
+//   shortest unique prefix of their IDs
+//
+// Alternative flows :-
+//  if the unit is not among the legal strings then return a message to the user
+//  if the storage cannot be read then return its error
+//
+func UpcomingActivities(count int, unit string, filter entities.Filter, getter CommandGetter, clock entities.Clock) (entities.Activities, error) {
+	now := clock.Now()
+	until, err := delayTimestamp(now, count, unit)
+	if err != nil {
+		return nil, err
+	}
+	activities, err := getter.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	shortenIds(activities)
+
+	upcoming := entities.Activities{}
+	for _, activity := range filter.Select(activities) {
+		upcoming = append(upcoming, activity.Occurrences(now, until)...)
+	}
+	upcoming.Sort()
+	return upcoming.OnlyThoseBefore(until), nil
+}
+
+// GetAgenda lists the upcoming activities a day at a time, each day under
+// a heading such as "Today", "Tomorrow" or "Friday". Occurrences of repeating
+// activities that have not been stored yet have their ID in parentheses.
+func GetAgenda(count int, unit string, filter entities.Filter, getter CommandGetter, clock entities.Clock) ([]string, error) {
+	upcoming, err := UpcomingActivities(count, unit, filter, getter, clock)
+	if err != nil {
+		return nil, err
+	}
+	today := clock.Now().In(entities.Zone)
+	output := []string{}
+	heading := ""
+	for _, activity := range upcoming {
+		if this := dayHeading(activity.Timestamp.In(entities.Zone), today); this != heading {
+			heading = this
+			output = append(output, heading)
+		}
+		output = append(output, "  "+agendaLine(activity))
+	}
+	return output, nil
+}
+
+// dayHeading names the day as seen from today: "Today", "Tomorrow", the
+// name of a day in the coming week, or else the date
+func dayHeading(day, today time.Time) string {
+	switch days := calendarDays(today, day); {
+	case days == 0:
+		return "Today"
+	case days == 1:
+		return "Tomorrow"
+	case days > 1 && days < 7:
+		return day.Weekday().String()
+	case day.Year() == today.Year():
+		return day.Format("Monday 2 January")
+	}
+	return day.Format("Monday 2 January 2006")
+}
+
+// calendarDays counts the midnights between the two dates, so that a
+// day with a daylight saving change is still one day
+func calendarDays(from, to time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+func agendaLine(activity entities.OneActivity) string {
+	id := fmt.Sprintf("[\033[1m%s\033[0m]", activity.DisplayId())
+	if activity.Id == "" {
+		id = fmt.Sprintf("(%s)", activity.DisplayId())
+	}
+	star := ""
+	if activity.HasRepeatCommand() {
+		star = "*"
+	}
+	return fmt.Sprintf("%s%s %s %s%s", id, star, activity.Timestamp.In(entities.Zone).Format("15:04"), activity.PriorityString(), activity.Body)
+}
@@ -0,0 +1,89 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+func TestGetAgenda(t *testing.T) {
+	entities.Zone = time.UTC
+	daily := activity("bbb222", "2014-01-01 09:00", "Duolingo")
+	daily.CommandTag = "every-n-days:1:from-09:00"
+	store := newFakeStore(
+		activity("aaa111", "2014-01-01 12:00", "Overdue"),
+		daily,
+		activity("ccc333", "2014-01-02 18:00", "Wash the car"),
+		activity("ddd444", "2014-01-03 12:00", "Cinema"),
+		activity("eee555", "2014-01-09 12:00", "Next week"),
+	)
+	// Thursday 2014-01-02
+	got, err := GetAgenda(2, "days", entities.Filter{}, store, clockAt("2014-01-02 08:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Today",
+		"  (bbb)* 09:00 Duolingo",
+		"  [\033[1mccc\033[0m] 18:00 Wash the car",
+		"Tomorrow",
+		"  (bbb)* 09:00 Duolingo",
+		"  [\033[1mddd\033[0m] 12:00 Cinema",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := GetAgenda(1, "fortnight", entities.Filter{}, store, clockAt("2014-01-02 08:00")); err == nil {
+		t.Errorf("got no error for a bad unit")
+	}
+}
+
+func TestUpcomingActivities(t *testing.T) {
+	weekly := activity("aaa111", "2014-01-03 12:00", "Bins")
+	weekly.CommandTag = "after-n-weeks:1"
+	store := newFakeStore(weekly)
+	got, err := UpcomingActivities(3, "weeks", entities.Filter{}, store, clockAt("2014-01-01 08:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stamps := []string{}
+	for _, activity := range got {
+		stamps = append(stamps, activity.DisplayId()+" "+activity.Timestamp.Format("2006-01-02"))
+	}
+	want := "aaa 2014-01-03, aaa 2014-01-10, aaa 2014-01-17"
+	if strings.Join(stamps, ", ") != want {
+		t.Errorf("got %q, want %q", strings.Join(stamps, ", "), want)
+	}
+	if got[0].Id != "aaa111" || got[1].Id != "" {
+		t.Errorf("got IDs %q and %q, want only the stored one to have an ID", got[0].Id, got[1].Id)
+	}
+}
+
+func TestDayHeading(t *testing.T) {
+	today := activity("", "2014-01-02 08:00", "").Timestamp // a Thursday
+	tests := []struct {
+		day  string
+		want string
+	}{
+		{"2014-01-02 23:59", "Today"},
+		{"2014-01-03 00:00", "Tomorrow"},
+		{"2014-01-04 12:00", "Saturday"},
+		{"2014-01-08 12:00", "Wednesday"},
+		{"2014-01-09 12:00", "Thursday 9 January"},
+		{"2015-01-09 12:00", "Friday 9 January 2015"},
+	}
+	for _, test := range tests {
+		if got := dayHeading(activity("", test.day, "").Timestamp, today); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.day, got, test.want)
+		}
+	}
+}
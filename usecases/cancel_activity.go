@@ -0,0 +1,38 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import "github.com/Fepelus/ActivityStream/entities"
+
+type CommandCanceller interface {
+	CommandFinder
+	Cancel(activity entities.OneActivity) error
+}
+
+//
+// Basic flow :-
+// The user passes the ID.
+// The usecase fetches the single matching activity
+// It gives the 'cancel' command to the canceller with this activity, so
+// that it is recorded as abandoned rather than done
+//
+// Alternative flows :-
+//  if the ID matches no activities then return a message to the user
+//  if the ID matches several activities then return them to the user and request a new ID
+//  if the storage cannot be read or written then return its error
+//  if the activity was done, cancelled or delayed elsewhere since it was
+//    found then return the storage's error
+//  if the activity repeats then it is cancelled all the same and no next
+//    occurrence is stored, as an activity only repeats once it is done
+//
+func CancelActivity(id string, canceller CommandCanceller) error {
+	thisActivity, err := findOnlyActivity(id, canceller)
+	if err != nil {
+		return err
+	}
+	return canceller.Cancel(thisActivity)
+}
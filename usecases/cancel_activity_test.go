@@ -0,0 +1,56 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCancelActivity(t *testing.T) {
+	due := activity("aaa111", "2014-01-01 12:00", "Duolingo")
+	due.CommandTag = "every-n-days:1"
+	store := newFakeStore(due, activity("bbb222", "2014-01-02 12:00", "Feed the cat"))
+	if err := CancelActivity("aaa", store); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.cancelled) != 1 || store.cancelled[0].Id != "aaa111" {
+		t.Errorf("cancelled %v, want aaa111", store.cancelled)
+	}
+	if len(store.done) != 0 || len(store.added) != 0 {
+		t.Errorf("done %v and added %v, want it not done and no next occurrence", store.done, store.added)
+	}
+	if len(store.activities) != 1 || store.activities[0].Id != "bbb222" {
+		t.Errorf("left %v, want bbb222", store.activities)
+	}
+}
+
+func TestCancelActivityAlternativeFlows(t *testing.T) {
+	broken := errors.New("disk on fire")
+	tests := []struct {
+		name string
+		id   string
+		err  error
+	}{
+		{"not found", "ccc", nil},
+		{"ambiguous", "aa", nil},
+		{"storage fails", "aaa1", broken},
+	}
+	for _, test := range tests {
+		store := newFakeStore(
+			activity("aaa111", "2014-01-01 12:00", "Wash the car"),
+			activity("aaa222", "2014-01-02 12:00", "Feed the cat"),
+		)
+		store.err = test.err
+		if err := CancelActivity(test.id, store); err == nil {
+			t.Errorf("%s: got no error", test.name)
+		}
+		if len(store.cancelled) != 0 {
+			t.Errorf("%s: cancelled %v, want nothing written", test.name, store.cancelled)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+type CommandCompacter interface {
+	Compact(archive bool) (int, string, error)
+}
+
+// CompactLog throws away the history of activities that have been deleted
+// so that the storage holds only the current ones. If archive is true the
+// history is kept aside rather than thrown away.
+// It returns how many entries were removed and where they were archived.
+func CompactLog(archive bool, compacter CommandCompacter) (int, string, error) {
+	return compacter.Compact(archive)
+}
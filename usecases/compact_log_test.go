@@ -0,0 +1,40 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"testing"
+
+	"github.com/Fepelus/ActivityStream/boundaries"
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+func TestCompactLog(t *testing.T) {
+	store := boundaries.MemoryStore()
+	car, _ := AddItem(activity("", "2014-01-01 12:00", "Wash the car"), store)
+	cat, _ := AddItem(activity("", "2014-01-02 12:00", "Feed the cat"), store)
+	if _, err := MarkActivityAsDone(cat, store, entities.SystemClock{}); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, archived, err := CompactLog(true, store)
+	if err != nil || removed != 2 || archived == "" {
+		t.Fatalf("got %d, %q, %v, want the cat's two lines archived", removed, archived, err)
+	}
+	if lines, err := GrepItems(cat, store); err != nil || len(lines) != 0 {
+		t.Errorf("grep got %q, %v, want the cat gone from the history", lines, err)
+	}
+	if lines, err := GrepItems(car, store); err != nil || len(lines) != 1 {
+		t.Errorf("grep got %q, %v, want the car still there", lines, err)
+	}
+	if undone, err := UndoOperations(1, store); err == nil {
+		t.Errorf("undid %q, want nothing left to undo", undone)
+	}
+	if removed, _, err := CompactLog(false, store); err != nil || removed != 0 {
+		t.Errorf("compacting again removed %d, %v, want nothing", removed, err)
+	}
+}
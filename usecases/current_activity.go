@@ -7,29 +7,61 @@ See LICENSE.txt for terms of usage.
 package usecases
 
 import (
-	"time"
+	"fmt"
 
 	"github.com/Fepelus/ActivityStream/entities"
 )
 
 type CommandGetter interface {
-	GetAll() entities.Activities
+	GetAll() (entities.Activities, error)
 }
 
-func GetActivity(getter CommandGetter) []string {
+// GetActivity lists the activities that pass the filter and are due by the
+// time the clock says, one line per activity, in the order named
+func GetActivity(filter entities.Filter, order string, getter CommandGetter, clock entities.Clock) ([]string, error) {
+	activities, err := DueActivities(filter, order, getter, clock)
+	if err != nil {
+		return nil, err
+	}
 	output := []string{}
-	activities := getter.GetAll()
+	for _, oneActivity := range activities {
+		output = append(output, oneActivity.IndexedString())
+	}
+	return output, nil
+}
+
+// DueActivities returns the activities that pass the filter with timestamps
+// earlier than the time the clock says. The order is "time" for earliest
+// first or "priority" for the most urgent first.
+func DueActivities(filter entities.Filter, order string, getter CommandGetter, clock entities.Clock) (entities.Activities, error) {
+	if order != "time" && order != "priority" {
+		return nil, fmt.Errorf("Order '%s' not found. Legal orders are 'time','priority'", order)
+	}
+	activities, err := getter.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	// index by the shortest ID prefix that is unique
+	shortenIds(activities)
+
+	// among all of them, so the index is the same however they are filtered
+	activities = filter.Select(activities)
 
 	// order by user-entered datestamp
 	activities.Sort()
 
 	// only return those before now
-	now := time.Now()
-	for _, oneActivity := range activities {
+	now := clock.Now()
+	for i, oneActivity := range activities {
 		if now.Before(oneActivity.Timestamp) {
+			activities = activities[0:i]
 			break
 		}
-		output = append(output, oneActivity.IndexedString())
 	}
-	return output
+
+	if order == "priority" {
+		activities.SortByPriority()
+	}
+	return activities, nil
 }
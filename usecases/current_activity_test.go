@@ -0,0 +1,100 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+func TestDueActivities(t *testing.T) {
+	store := newFakeStore(
+		activity("ccc333", "2014-01-03 12:01", "Later"),
+		activity("bbb222", "2014-01-02 12:00", "Second"),
+		activity("abc111", "2014-01-01 12:00", "First"),
+		activity("abd444", "2014-01-01 12:00", "Also first"),
+	)
+	due, err := DueActivities(entities.Filter{}, "time", store, clockAt("2014-01-03 12:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, activity := range due {
+		got = append(got, activity.ShortId+" "+activity.Body)
+	}
+	want := []string{"abd Also first", "abc First", "bbb Second"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestGetActivity(t *testing.T) {
+	entities.Zone = time.UTC
+	store := newFakeStore(activity("aaa111", "2014-01-01 12:00", "Wash the car"))
+	lines, err := GetActivity(entities.Filter{}, "time", store, clockAt("2014-01-03 12:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[\033[1maaa\033[0m] 2014-01-01 12:00 Wash the car"
+	if len(lines) != 1 || lines[0] != want {
+		t.Errorf("got %q, want %q", lines, want)
+	}
+
+	broken := errors.New("disk on fire")
+	store.err = broken
+	if _, err := GetActivity(entities.Filter{}, "time", store, clockAt("2014-01-03 12:00")); err != broken {
+		t.Errorf("got %v, want the storage's error", err)
+	}
+}
+
+func TestDueActivitiesFiltered(t *testing.T) {
+	store := newFakeStore(
+		activity("abc111", "2014-01-01 12:00", "Fix the pager +oncall"),
+		activity("abd222", "2014-01-01 13:00", "Water the plants @home"),
+	)
+	filter, _ := entities.ParseFilter([]string{"-@home"})
+	due, err := DueActivities(filter, "time", store, clockAt("2014-01-03 12:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || due[0].ShortId != "abc" {
+		t.Errorf("got %+v, want the pager with the index it has among all", due)
+	}
+}
+
+func TestDueActivitiesByPriority(t *testing.T) {
+	urgent := activity("bbb222", "2014-01-02 12:00", "Renew the passport")
+	urgent.Priority = 1
+	low := activity("ccc333", "2014-01-01 09:00", "Tidy the shed")
+	low.Priority = 3
+	notYet := activity("ddd444", "2014-01-04 12:00", "Not due yet")
+	notYet.Priority = 1
+	store := newFakeStore(
+		activity("aaa111", "2014-01-01 08:00", "Wash the car"),
+		urgent, low, notYet,
+	)
+	due, err := DueActivities(entities.Filter{}, "priority", store, clockAt("2014-01-03 12:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, activity := range due {
+		got = append(got, activity.Body)
+	}
+	want := []string{"Renew the passport", "Tidy the shed", "Wash the car"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := DueActivities(entities.Filter{}, "size", store, clockAt("2014-01-03 12:00")); err == nil {
+		t.Error("got no error for an unknown order")
+	}
+}
@@ -10,61 +10,61 @@ import (
 	"fmt"
 	"time"
 
-	"bytes"
 	"github.com/Fepelus/ActivityStream/entities"
 )
 
+// CommandReplacer deletes one activity and adds another in one step, so
+// that nobody sees one without the other. The operation names what the user
+// did, for example "delay". It returns the ID of the new activity.
+type CommandReplacer interface {
+	Replace(old, replacement entities.OneActivity, operation string) (string, error)
+}
+
 type CommandDelayer interface {
 	CommandDeleter
 	CommandAdder
+	CommandReplacer
 }
 
 //
 // Basic flow :-
 // The user passes the ID.
 // The usecase fetches the single matching activity
-// It gives the 'done' command to the delayer with this activity
 // It creates a new command with the same details as the old command
 // It alters the timestamp of the new command
-// It sends the new command to the delayer to store as a new command
-// And returns the hash id of the new command
+// It sends both to the delayer to replace the old command with the new one
+// And returns the shortest unique prefix of the hash id of the new command
 //
 // Alternative flows :-
 //  if the ID matches no activities then return a message to the user
 //  if the ID matches several activities then return them to the user and request a new ID
+//  if the storage cannot be read or written then return its error
 //  if the unit is not among the legal strings then return a message to the user
+//  if the activity was done, cancelled or delayed elsewhere since it was
+//    found then return the storage's error
 //  In any of the alternative flows, no entries are written to the delayer.
 //
 func DelayActivity(id string, count int, unit string, delayer CommandDelayer) (string, error) {
-	activities := delayer.FindActivity(id)
-
-	if len(activities) == 0 {
-		return "", fmt.Errorf("No activities found with index %s\n", id)
-	}
-	if len(activities) > 1 {
-		var buffer bytes.Buffer
-		buffer.WriteString("Ambiguous ID matches:\n")
-		for i := 0; i < len(activities); i++ {
-			buffer.WriteString(activities[i].IndexedString())
-			buffer.WriteString("\n")
-		}
-		buffer.WriteString("\nNothing has been deleted. You may try again.\n")
-		return "", fmt.Errorf(buffer.String())
+	thisActivity, err := findOnlyActivity(id, delayer)
+	if err != nil {
+		return "", err
 	}
-	thisActivity := activities[0]
 
 	newtimestamp, err := delayTimestamp(thisActivity.Timestamp, count, unit)
 	if err != nil {
 		return "", err
 	}
-	delayer.Delete(activities[0])
-    newHashId := delayer.AddNew(entities.OneActivity{
-		"",
-		newtimestamp,
-		thisActivity.CommandTag,
-		thisActivity.Body,
-	})
-	return newHashId, nil
+	newHashId, err := delayer.Replace(thisActivity, entities.OneActivity{
+		Timestamp:   newtimestamp,
+		CommandTag:  thisActivity.CommandTag,
+		Priority:    thisActivity.Priority,
+		Body:        thisActivity.Body,
+		Predecessor: thisActivity.Id,
+	}, "delay")
+	if err != nil {
+		return "", err
+	}
+	return shortIdAmongLive(newHashId, delayer), nil
 }
 
 func delayTimestamp(input time.Time, count int, unit string) (time.Time, error) {
@@ -0,0 +1,122 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDelayActivity(t *testing.T) {
+	tests := []struct {
+		count int
+		unit  string
+		want  string
+	}{
+		{1, "day", "2014-01-02 12:00"},
+		{2, "days", "2014-01-03 12:00"},
+		{1, "week", "2014-01-08 12:00"},
+		{1, "month", "2014-02-01 12:00"},
+		{3, "hours", "2014-01-01 15:00"},
+		{90, "minutes", "2014-01-01 13:30"},
+	}
+	for _, test := range tests {
+		store := newFakeStore(activity("aaa111", "2014-01-01 12:00", "Wash the car"))
+		hash, err := DelayActivity("aaa", test.count, test.unit, store)
+		if err != nil {
+			t.Errorf("%d %s: %s", test.count, test.unit, err)
+			continue
+		}
+		if len(store.replaced) != 1 || store.replaced[0] != "delay" {
+			t.Errorf("%d %s: replaced with %v, want one \"delay\"", test.count, test.unit, store.replaced)
+			continue
+		}
+		delayed := store.added[0]
+		if got := delayed.Timestamp.Format("2006-01-02 15:04"); got != test.want {
+			t.Errorf("%d %s: delayed to %s, want %s", test.count, test.unit, got, test.want)
+		}
+		if delayed.Body != "Wash the car" {
+			t.Errorf("%d %s: body is %q", test.count, test.unit, delayed.Body)
+		}
+		if delayed.Predecessor != "aaa111" {
+			t.Errorf("%d %s: delayed from %q, want the car it replaced", test.count, test.unit, delayed.Predecessor)
+		}
+		if hash != delayed.Id[0:minIdLength] {
+			t.Errorf("%d %s: returned %q, want %q", test.count, test.unit, hash, delayed.Id[0:minIdLength])
+		}
+	}
+}
+
+func TestDelayActivityAlternativeFlows(t *testing.T) {
+	broken := errors.New("disk on fire")
+	tests := []struct {
+		name string
+		id   string
+		unit string
+		err  error
+	}{
+		{"not found", "ccc", "day", nil},
+		{"ambiguous", "aa", "day", nil},
+		{"bad unit", "aaa1", "fortnight", nil},
+		{"storage fails", "aaa1", "day", broken},
+	}
+	for _, test := range tests {
+		store := newFakeStore(
+			activity("aaa111", "2014-01-01 12:00", "Wash the car"),
+			activity("aaa222", "2014-01-02 12:00", "Feed the cat"),
+		)
+		store.err = test.err
+		_, err := DelayActivity(test.id, 1, test.unit, store)
+		if err == nil {
+			t.Errorf("%s: got no error", test.name)
+		}
+		if len(store.replaced) != 0 {
+			t.Errorf("%s: replaced %v, want nothing written", test.name, store.replaced)
+		}
+	}
+
+	var notFound *NotFoundError
+	if _, err := DelayActivity("ccc", 1, "day", newFakeStore()); !errors.As(err, &notFound) {
+		t.Errorf("got %v, want a NotFoundError", err)
+	}
+	var ambiguous *AmbiguousIdError
+	store := newFakeStore(activity("aaa111", "2014-01-01 12:00", "one"), activity("aaa222", "2014-01-01 12:00", "two"))
+	if _, err := DelayActivity("aa", 1, "day", store); !errors.As(err, &ambiguous) {
+		t.Errorf("got %v, want an AmbiguousIdError", err)
+	}
+}
+
+func TestRescheduleActivity(t *testing.T) {
+	store := newFakeStore(activity("aaa111", "2014-01-01 12:00", "Wash the car"))
+	when := activity("", "2014-08-19 11:00", "").Timestamp
+	if _, err := RescheduleActivity("aaa", when, store); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.replaced) != 1 || store.replaced[0] != "reschedule" {
+		t.Fatalf("replaced with %v, want one \"reschedule\"", store.replaced)
+	}
+	if !store.added[0].Timestamp.Equal(when) {
+		t.Errorf("rescheduled to %s, want %s", store.added[0].Timestamp, when)
+	}
+}
+
+func TestDelayAndRescheduleKeepPriority(t *testing.T) {
+	urgent := activity("aaa111", "2014-01-01 12:00", "Renew the passport")
+	urgent.Priority = 1
+	store := newFakeStore(urgent)
+	if _, err := DelayActivity("aaa", 1, "day", store); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RescheduleActivity(store.added[0].Id, urgent.Timestamp, store); err != nil {
+		t.Fatal(err)
+	}
+	for _, added := range store.added {
+		if added.Priority != 1 {
+			t.Errorf("got priority %d for %s, want 1", added.Priority, added.FullString())
+		}
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+type CommandEditor interface {
+	CommandGetter
+	CommandFinder
+	Edit(activity entities.OneActivity) error
+}
+
+//
+// Basic flow :-
+// The user passes the ID and a way to change the text of the activity,
+// such as a text editor.
+// The usecase fetches the single matching activity
+// It passes the text of the activity, as written after its time, to be
+// changed, and reads the repeat rule, priority and body from what it gets
+// back
+// It sends the activity, with its ID and timestamp as they were, to the
+// editor to record the change
+// And returns the shortest unique prefix of the ID, which is the same as
+// before
+//
+// Alternative flows :-
+//  if the ID matches no activities then return a message to the user
+//  if the ID matches several activities then return them to the user and request a new ID
+//  if the text could not be changed then return why
+//  if the new text is empty then return a message to the user
+//  if the new text has a repeat rule that cannot be understood then return
+//    a message to the user
+//  if the text is the same as before then nothing is written
+//  if the storage cannot be read or written then return its error
+//  if the activity was done, cancelled or delayed elsewhere since it was
+//    found then return the storage's error
+//
+func EditActivity(id string, edit func(text string) (string, error), editor CommandEditor) (string, error) {
+	thisActivity, err := findOnlyActivity(id, editor)
+	if err != nil {
+		return "", err
+	}
+
+	text, err := edit(thisActivity.Text())
+	if err != nil {
+		return "", err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("Expected the new text of the activity but got nothing")
+	}
+	edited := thisActivity.WithText(text)
+	if edited.HasRepeatCommand() {
+		if _, err := entities.ParseRepeatRule(edited.CommandTag); err != nil {
+			return "", err
+		}
+	}
+	if edited.Text() != thisActivity.Text() {
+		if err := editor.Edit(edited); err != nil {
+			return "", err
+		}
+	}
+	return shortIdAmongLive(edited.Id, editor), nil
+}
+
+// NewText is the change to an activity's text that replaces it with text
+func NewText(text string) func(string) (string, error) {
+	return func(string) (string, error) {
+		return text, nil
+	}
+}
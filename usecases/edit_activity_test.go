@@ -0,0 +1,75 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEditActivity(t *testing.T) {
+	store := newFakeStore(activity("aaa111", "2014-01-01 12:00", "Wash the cra"))
+	shown := ""
+	id, err := EditActivity("aaa", func(text string) (string, error) {
+		shown = text
+		return "@rtask:every-n-weeks:1 !2 Wash the car\n", nil
+	}, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shown != "Wash the cra" || id != "aaa" {
+		t.Errorf("showed %q and returned %q", shown, id)
+	}
+	if len(store.edited) != 1 || len(store.added) != 0 || len(store.deleted) != 0 {
+		t.Fatalf("edited %v, added %v, deleted %v, want one edit", store.edited, store.added, store.deleted)
+	}
+	edited := store.edited[0]
+	if edited.Id != "aaa111" || edited.Timestamp.Format("2006-01-02 15:04") != "2014-01-01 12:00" {
+		t.Errorf("got %s %s, want the ID and time kept", edited.Id, edited.Timestamp)
+	}
+	if edited.CommandTag != "every-n-weeks:1" || edited.Priority != 2 || edited.Body != "Wash the car" {
+		t.Errorf("got %q", edited.FullString())
+	}
+}
+
+func TestEditActivityAlternativeFlows(t *testing.T) {
+	broken := errors.New("disk on fire")
+	tests := []struct {
+		name    string
+		id      string
+		text    string
+		err     error
+		wantErr bool
+	}{
+		{name: "not found", id: "ccc", text: "Feed the dog", wantErr: true},
+		{name: "ambiguous", id: "aa", text: "Feed the dog", wantErr: true},
+		{name: "empty", id: "aaa1", text: " \n", wantErr: true},
+		{name: "bad rule", id: "aaa1", text: "@rtask:every-n-fortnights:1 Feed the dog", wantErr: true},
+		{name: "storage fails", id: "aaa1", text: "Feed the dog", err: broken, wantErr: true},
+		{name: "editor fails", id: "aaa1", err: broken, wantErr: true},
+		{name: "unchanged", id: "aaa1", text: "Wash the car"},
+	}
+	for _, test := range tests {
+		store := newFakeStore(
+			activity("aaa111", "2014-01-01 12:00", "Wash the car"),
+			activity("aaa222", "2014-01-02 12:00", "Feed the cat"),
+		)
+		edit := NewText(test.text)
+		if test.name == "editor fails" {
+			edit = func(string) (string, error) { return "", broken }
+		} else {
+			store.err = test.err
+		}
+		_, err := EditActivity(test.id, edit, store)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: got %v", test.name, err)
+		}
+		if len(store.edited) != 0 {
+			t.Errorf("%s: edited %v, want nothing written", test.name, store.edited)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+// NotFoundError is returned when no current activity has an ID starting with Id
+type NotFoundError struct {
+	Id string
+}
+
+func (this *NotFoundError) Error() string {
+	return fmt.Sprintf("No activities found with index %s", this.Id)
+}
+
+// AmbiguousIdError is returned when more than one current activity has an ID
+// starting with Id. The Candidates have ShortIds that tell them apart.
+type AmbiguousIdError struct {
+	Id         string
+	Candidates entities.Activities
+}
+
+func (this *AmbiguousIdError) Error() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("Ambiguous ID matches:\n")
+	for i := 0; i < len(this.Candidates); i++ {
+		buffer.WriteString(this.Candidates[i].IndexedString())
+		buffer.WriteString("\n")
+	}
+	buffer.WriteString("\nNothing has been changed. You may try again.")
+	return buffer.String()
+}
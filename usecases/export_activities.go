@@ -0,0 +1,21 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import "github.com/Fepelus/ActivityStream/entities"
+
+// LiveActivities returns every activity that has not been deleted, due or
+// not, earliest first, for handing on to other programs
+func LiveActivities(getter CommandGetter) (entities.Activities, error) {
+	activities, err := getter.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	shortenIds(activities)
+	activities.Sort()
+	return activities, nil
+}
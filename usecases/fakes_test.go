@@ -0,0 +1,204 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+// fakeStore is an in-memory Store for the use cases to work on. It keeps
+// the live activities in the order they were added and records what the
+// use cases asked of it. If err is set then every method returns it
+// without changing anything.
+type fakeStore struct {
+	activities entities.Activities
+	lines      []string
+	operations []entities.Operation
+	added      entities.Activities
+	deleted    entities.Activities
+	done       entities.Activities
+	cancelled  entities.Activities
+	replaced   []string
+	edited     entities.Activities
+	err        error
+}
+
+var _ Store = &fakeStore{}
+
+func newFakeStore(activities ...entities.OneActivity) *fakeStore {
+	return &fakeStore{activities: activities}
+}
+
+// activity makes a stored activity with the given ID, due at "YYYY-MM-DD HH:MM" UTC
+func activity(id, stamp, body string) entities.OneActivity {
+	timestamp, err := time.ParseInLocation("2006-01-02 15:04", stamp, time.UTC)
+	if err != nil {
+		panic(err)
+	}
+	return entities.OneActivity{Id: id, Timestamp: timestamp, Body: body}
+}
+
+// clockAt is a clock stopped at "YYYY-MM-DD HH:MM" UTC
+func clockAt(stamp string) entities.Clock {
+	return entities.FixedClock{Time: activity("", stamp, "").Timestamp}
+}
+
+func fakeId(activity entities.OneActivity) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(activity.FullString())))
+}
+
+func (this *fakeStore) GetAll() (entities.Activities, error) {
+	if this.err != nil {
+		return nil, this.err
+	}
+	return append(entities.Activities{}, this.activities...), nil
+}
+
+func (this *fakeStore) FindActivity(id string) (entities.Activities, error) {
+	if this.err != nil {
+		return nil, this.err
+	}
+	found := entities.Activities{}
+	for _, activity := range this.activities {
+		if strings.HasPrefix(activity.Id, id) {
+			found = append(found, activity)
+		}
+	}
+	return found, nil
+}
+
+func (this *fakeStore) AddNew(activity entities.OneActivity) (string, error) {
+	if this.err != nil {
+		return "", this.err
+	}
+	activity.Id = fakeId(activity)
+	this.activities = append(this.activities, activity)
+	this.added = append(this.added, activity)
+	return activity.Id, nil
+}
+
+func (this *fakeStore) Delete(activity entities.OneActivity) error {
+	if this.err != nil {
+		return this.err
+	}
+	this.remove(activity.Id)
+	this.deleted = append(this.deleted, activity)
+	return nil
+}
+
+func (this *fakeStore) Done(activity entities.OneActivity) error {
+	if this.err != nil {
+		return this.err
+	}
+	this.remove(activity.Id)
+	this.done = append(this.done, activity)
+	return nil
+}
+
+func (this *fakeStore) Cancel(activity entities.OneActivity) error {
+	if this.err != nil {
+		return this.err
+	}
+	this.remove(activity.Id)
+	this.cancelled = append(this.cancelled, activity)
+	return nil
+}
+
+// Repeat is Done and AddNew together
+func (this *fakeStore) Repeat(done, next entities.OneActivity) (string, error) {
+	if this.err != nil {
+		return "", this.err
+	}
+	this.Done(done)
+	return this.AddNew(next)
+}
+
+func (this *fakeStore) Replace(old, replacement entities.OneActivity, operation string) (string, error) {
+	if this.err != nil {
+		return "", this.err
+	}
+	this.remove(old.Id)
+	this.deleted = append(this.deleted, old)
+	replacement.Id = fakeId(replacement)
+	this.activities = append(this.activities, replacement)
+	this.added = append(this.added, replacement)
+	this.replaced = append(this.replaced, operation)
+	return replacement.Id, nil
+}
+
+func (this *fakeStore) Edit(activity entities.OneActivity) error {
+	if this.err != nil {
+		return this.err
+	}
+	for i := range this.activities {
+		if this.activities[i].Id == activity.Id {
+			this.activities[i] = activity
+		}
+	}
+	this.edited = append(this.edited, activity)
+	return nil
+}
+
+func (this *fakeStore) remove(id string) {
+	kept := entities.Activities{}
+	for _, activity := range this.activities {
+		if activity.Id != id {
+			kept = append(kept, activity)
+		}
+	}
+	this.activities = kept
+}
+
+func (this *fakeStore) Grep(id string) ([]string, error) {
+	if this.err != nil {
+		return nil, this.err
+	}
+	found := []string{}
+	for _, line := range this.lines {
+		if strings.Contains(line, id) {
+			found = append(found, line)
+		}
+	}
+	return found, nil
+}
+
+// Compact and Migrate are only here to make the fake a Store; their use
+// cases are tested against the real storage
+func (this *fakeStore) Compact(archive bool) (int, string, error) {
+	return 0, "", this.err
+}
+
+func (this *fakeStore) Migrate() (int, error) {
+	return 0, this.err
+}
+
+// History is the operations, oldest first
+func (this *fakeStore) History() ([]entities.Operation, error) {
+	if this.err != nil {
+		return nil, this.err
+	}
+	return append([]entities.Operation{}, this.operations...), nil
+}
+
+// Undo hands back the most recent operations, newest first
+func (this *fakeStore) Undo(n int) ([]entities.Operation, error) {
+	if this.err != nil {
+		return nil, this.err
+	}
+	undone := []entities.Operation{}
+	for len(undone) < n && len(this.operations) > 0 {
+		last := len(this.operations) - 1
+		undone = append(undone, this.operations[last])
+		this.operations = this.operations[0:last]
+	}
+	return undone, nil
+}
@@ -7,9 +7,9 @@ See LICENSE.txt for terms of usage.
 package usecases
 
 type CommandGrepper interface {
-	Grep(id string) []string
+	Grep(id string) ([]string, error)
 }
 
-func GrepItems(id string, grepper CommandGrepper) []string {
+func GrepItems(id string, grepper CommandGrepper) ([]string, error) {
 	return grepper.Grep(id)
 }
@@ -0,0 +1,46 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGrepItems(t *testing.T) {
+	store := newFakeStore()
+	store.lines = []string{
+		"[2014-07-13T19:24:09] ADD: (aaa111) 2014-01-01 12:00 UTC Wash the car",
+		"[2014-07-13T19:24:09] ADD: (bbb222) 2014-01-02 12:00 UTC Feed the cat",
+		"[2014-07-14T08:00:00] DELETE: (aaa111) 2014-01-01 12:00 UTC Wash the car",
+	}
+	tests := []struct {
+		id   string
+		want []string
+	}{
+		{"(aaa", []string{store.lines[0], store.lines[2]}},
+		{"(bbb222", []string{store.lines[1]}},
+		{"(ccc", []string{}},
+	}
+	for _, test := range tests {
+		got, err := GrepItems(test.id, store)
+		if err != nil {
+			t.Errorf("%s: %s", test.id, err)
+			continue
+		}
+		if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+			t.Errorf("%s: got %q, want %q", test.id, got, test.want)
+		}
+	}
+
+	broken := errors.New("disk on fire")
+	store.err = broken
+	if _, err := GrepItems("(aaa", store); err != broken {
+		t.Errorf("got %v, want the storage's error", err)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"sort"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+// IDs are never shown shorter than this, however few activities there are
+const minIdLength = 3
+
+// uniqueIdLength is the length of the shortest prefix that tells apart
+// the IDs of all the given activities. An activity may be given twice.
+func uniqueIdLength(activities entities.Activities) int {
+	ids := make([]string, len(activities))
+	for i, activity := range activities {
+		ids[i] = activity.Id
+	}
+	sort.Strings(ids)
+
+	length := minIdLength
+	for i := 1; i < len(ids); i++ {
+		if ids[i-1] == ids[i] {
+			continue
+		}
+		if shared := commonPrefixLength(ids[i-1], ids[i]) + 1; shared > length {
+			length = shared
+		}
+	}
+	return length
+}
+
+func commonPrefixLength(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// shortenIds sets the ShortId of each activity to the shortest prefix
+// that is unique among them.
+func shortenIds(activities entities.Activities) {
+	length := uniqueIdLength(activities)
+	for i := range activities {
+		activities[i].ShortId = prefix(activities[i].Id, length)
+	}
+}
+
+func prefix(id string, length int) string {
+	if len(id) < length {
+		return id
+	}
+	return id[0:length]
+}
+
+// shortIdAmongLive abbreviates the given ID just enough to tell it apart
+// from all the activities that have not been deleted.
+// It is only used once the ID has been stored, so if the other activities
+// cannot be read then the whole ID is returned rather than an error.
+func shortIdAmongLive(id string, getter CommandGetter) string {
+	activities, err := getter.GetAll()
+	if err != nil {
+		return id
+	}
+	return prefix(id, uniqueIdLength(activities))
+}
+
+// findOnlyActivity returns the single activity whose ID starts with the
+// given ID, a *NotFoundError if there is none or an *AmbiguousIdError if
+// there are more than one.
+func findOnlyActivity(id string, finder CommandFinder) (entities.OneActivity, error) {
+	activities, err := finder.FindActivity(id)
+	if err != nil {
+		return entities.OneActivity{}, err
+	}
+
+	if len(activities) == 0 {
+		return entities.OneActivity{}, &NotFoundError{id}
+	}
+	if len(activities) > 1 {
+		shortenIds(activities)
+		return entities.OneActivity{}, &AmbiguousIdError{id, activities}
+	}
+	return activities[0], nil
+}
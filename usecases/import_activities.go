@@ -0,0 +1,75 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import "github.com/Fepelus/ActivityStream/entities"
+
+type CommandImporter interface {
+	CommandAdder
+	CommandHistorian
+}
+
+//
+// Basic flow :-
+// The user passes activities read from elsewhere, each with the ID it was
+// exported with if it was exported by acts.
+// The usecase fetches the current activities and the history of the stream
+// It sends each activity whose ID has never been in the stream, and that is
+// not among the current activities due at the same time with the same text,
+// repeat rule and priority, to the adder
+// And returns the full IDs of the activities added and how many were
+// already there
+//
+// Alternative flows :-
+//  if an activity comes twice then only the first is added
+//  if an activity's ID is in the stream, because it is still to be done or
+//   was done, cancelled, delayed or changed since it was exported, then it is
+//   not added
+//  if an activity has no ID then it is only compared by what it says
+//  if the storage cannot be read or written then return its error along with
+//   the IDs of the activities added before it failed
+//
+func ImportActivities(activities entities.Activities, importer CommandImporter) ([]string, int, error) {
+	live, err := importer.GetAll()
+	if err != nil {
+		return nil, 0, err
+	}
+	history, err := importer.History()
+	if err != nil {
+		return nil, 0, err
+	}
+	known := map[string]bool{}
+	seen := map[string]bool{}
+	for _, activity := range live {
+		known[activity.Id] = true
+		seen[activity.FullString()] = true
+	}
+	for _, operation := range history {
+		for _, change := range operation.Changes {
+			known[change.Activity.Id] = true
+		}
+	}
+
+	added := []string{}
+	skipped := 0
+	for _, activity := range activities {
+		if (activity.Id != "" && known[activity.Id]) || seen[activity.FullString()] {
+			skipped++
+			continue
+		}
+		id, err := importer.AddNew(activity)
+		if err != nil {
+			return added, skipped, err
+		}
+		if activity.Id != "" {
+			known[activity.Id] = true
+		}
+		seen[activity.FullString()] = true
+		added = append(added, id)
+	}
+	return added, skipped, nil
+}
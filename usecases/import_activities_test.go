@@ -0,0 +1,93 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+// imported is an activity as read from elsewhere, which has no ID
+func imported(stamp, body string) entities.OneActivity {
+	return activity("", stamp, body)
+}
+
+func TestImportActivities(t *testing.T) {
+	store := newFakeStore(activity("aaa111", "2014-01-01 12:00", "Wash the car"))
+	urgent := imported("2014-01-03 12:00", "Feed the cat")
+	urgent.Priority = 1
+	added, skipped, err := ImportActivities(entities.Activities{
+		imported("2014-01-02 12:00", "Feed the cat"),
+		imported("2014-01-01 12:00", "Wash the car"),
+		imported("2014-01-02 12:00", "Feed the cat"),
+		urgent,
+	}, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 2 || skipped != 2 {
+		t.Errorf("added %v and skipped %d, want 2 of each", added, skipped)
+	}
+	if len(store.added) != 2 || store.added[0].Body != "Feed the cat" || store.added[1].Priority != 1 {
+		t.Errorf("stored %+v", store.added)
+	}
+}
+
+func TestImportActivitiesByTheirIds(t *testing.T) {
+	car := activity("aaa111", "2014-01-01 12:00", "Wash the car")
+	delayed := activity("bbb222", "2014-01-02 12:00", "Wash the car")
+	store := newFakeStore(delayed, activity("ccc333", "2014-01-01 12:00", "Feed the cat"))
+	store.operations = []entities.Operation{
+		operation("new", "2014-01-01 08:00", change(entities.AddCommand, car)),
+		operation("delay", "2014-01-01 13:00", change(entities.DeleteCommand, car), change(entities.AddCommand, delayed)),
+	}
+	movedInTheCalendar := activity("ccc333", "2014-01-05 12:00", "Feed the cat")
+	sameAsTheCat := activity("ddd444", "2014-01-01 12:00", "Feed the cat")
+	fromElsewhere := activity("eee555", "2014-01-03 12:00", "Water the plants")
+	added, skipped, err := ImportActivities(entities.Activities{
+		car,
+		movedInTheCalendar,
+		sameAsTheCat,
+		fromElsewhere,
+		fromElsewhere,
+	}, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(added) != 1 || skipped != 4 {
+		t.Errorf("added %v and skipped %d, want 1 and 4", added, skipped)
+	}
+	if len(store.added) != 1 || store.added[0].Body != "Water the plants" {
+		t.Errorf("stored %+v, want only the plants", store.added)
+	}
+}
+
+func TestImportActivitiesStorageFails(t *testing.T) {
+	broken := errors.New("disk on fire")
+	store := newFakeStore()
+	store.err = broken
+	added, _, err := ImportActivities(entities.Activities{imported("2014-01-02 12:00", "Feed the cat")}, store)
+	if err != broken || len(added) != 0 {
+		t.Errorf("got %v %v, want the storage's error and nothing added", added, err)
+	}
+}
+
+func TestLiveActivities(t *testing.T) {
+	store := newFakeStore(
+		activity("bbb222", "2099-01-02 12:00", "Later"),
+		activity("aaa111", "2014-01-01 12:00", "Sooner"),
+	)
+	live, err := LiveActivities(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(live) != 2 || live[0].Body != "Sooner" || live[1].ShortId != "bbb" {
+		t.Errorf("got %+v", live)
+	}
+}
@@ -0,0 +1,18 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+type CommandMigrator interface {
+	Migrate() (int, error)
+}
+
+// MigrateIds gives fresh IDs to activities that share one with another,
+// as identical activities did when an ID was made from the content.
+// It returns how many activities were given new IDs.
+func MigrateIds(migrator CommandMigrator) (int, error) {
+	return migrator.Migrate()
+}
@@ -0,0 +1,46 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Fepelus/ActivityStream/boundaries"
+)
+
+func TestMigrateIds(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "logfile.txt")
+	sha := "414a4ec94c5b4c0f859b5f7cf721fceba05b4d84"
+	// an old logfile, in which identical activities shared an ID
+	os.WriteFile(filename, []byte(
+		"[2014-07-13T19:24:09] ADD: ("+sha+") 2014-05-05 05:07 UTC Bam!\n"+
+			"[2014-07-13T19:25:09] ADD: ("+sha+") 2014-05-05 05:07 UTC Bam!\n"), 0600)
+	store := boundaries.FileStore(filename)
+
+	if live, err := LiveActivities(store); err != nil || len(live) != 1 {
+		t.Fatalf("got %+v, %v before migrating, want the copies seen as one", live, err)
+	}
+	migrated, err := MigrateIds(store)
+	if err != nil || migrated != 1 {
+		t.Fatalf("got %d, %v, want one of the two given a new ID", migrated, err)
+	}
+	if live, err := LiveActivities(store); err != nil || len(live) != 2 {
+		t.Fatalf("got %+v, %v after migrating, want both copies", live, err)
+	}
+	if _, err := DelayActivity(sha, 1, "day", store); err != nil {
+		t.Fatalf("delay got %v, want the copy that kept the ID delayed", err)
+	}
+	live, _ := LiveActivities(store)
+	if len(live) != 2 || live[0].Timestamp.Day() != 5 || live[1].Timestamp.Day() != 6 {
+		t.Errorf("got %+v, want only one copy delayed", live)
+	}
+	if migrated, err := MigrateIds(store); err != nil || migrated != 0 {
+		t.Errorf("migrating again got %d, %v, want nothing to do", migrated, err)
+	}
+}
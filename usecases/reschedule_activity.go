@@ -0,0 +1,49 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+//
+// Basic flow :-
+// The user passes the ID and the new timestamp.
+// The usecase fetches the single matching activity
+// It creates a new command with the same details as the old command
+// but with the new timestamp
+// It sends both to the delayer to replace the old command with the new one
+// And returns the shortest unique prefix of the hash id of the new command
+//
+// Alternative flows :-
+//  if the ID matches no activities then return a message to the user
+//  if the ID matches several activities then return them to the user and request a new ID
+//  if the storage cannot be read or written then return its error
+//  if the activity was done, cancelled or delayed elsewhere since it was
+//    found then return the storage's error
+//  In any of the alternative flows, no entries are written to the delayer.
+//
+func RescheduleActivity(id string, newtimestamp time.Time, delayer CommandDelayer) (string, error) {
+	thisActivity, err := findOnlyActivity(id, delayer)
+	if err != nil {
+		return "", err
+	}
+
+	newHashId, err := delayer.Replace(thisActivity, entities.OneActivity{
+		Timestamp:   newtimestamp,
+		CommandTag:  thisActivity.CommandTag,
+		Priority:    thisActivity.Priority,
+		Body:        thisActivity.Body,
+		Predecessor: thisActivity.Id,
+	}, "reschedule")
+	if err != nil {
+		return "", err
+	}
+	return shortIdAmongLive(newHashId, delayer), nil
+}
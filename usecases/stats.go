@@ -0,0 +1,391 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+type CommandHistorian interface {
+	CommandGetter
+	History() ([]entities.Operation, error)
+}
+
+// Stats summarises how the activities were done over a period that ends now
+type Stats struct {
+	From  time.Time
+	Until time.Time
+	// Days are each day of the period in the configured Zone, and Weeks
+	// each week from Monday that it touches, oldest first. They count only
+	// what happened within the period.
+	Days      []Tally
+	Weeks     []Tally
+	Done      int
+	Cancelled int
+	// Late is how many of those done were done after they were due, and
+	// AverageLateness how long after they were due they were done on
+	// average, which is less than nothing if they were done early
+	Late            int
+	AverageLateness time.Duration
+	// MostDelayed are the activities delayed or rescheduled within the
+	// period, those delayed the most times first
+	MostDelayed []DelayChain
+	// Streaks are the repeating activities that have not been done or
+	// cancelled, the longest streak first
+	Streaks []Streak
+}
+
+// Tally counts what was done and cancelled from Start until the next Tally
+type Tally struct {
+	Start     time.Time
+	Done      int
+	Cancelled int
+}
+
+// DelayChain is an activity that was delayed or rescheduled, as it is now
+// or was when it was done or cancelled
+type DelayChain struct {
+	Activity entities.OneActivity
+	Delays   int
+	// Slipped is how much later it is due than it first was
+	Slipped time.Duration
+	// Outcome is "done" or "cancelled", or "" if it is still to be done
+	Outcome string
+}
+
+// Streak is how many of the occurrences before a repeating activity were
+// done in a row before the one after each would have fallen due
+type Streak struct {
+	Activity entities.OneActivity
+	Length   int
+}
+
+// mostDelayedShown is how many of the most delayed activities are reported
+const mostDelayedShown = 5
+
+// a link says how an activity came from the one before it
+type link struct {
+	from entities.OneActivity
+	at   time.Time
+	// repeated is true if from was done and this is its next occurrence,
+	// otherwise this is from delayed or rescheduled
+	repeated bool
+}
+
+//
+// Basic flow :-
+// The user passes how far back to look, as a count and a unit.
+// The usecase fetches the history of the operations that have not been undone
+// It counts the activities done and cancelled on each day and in each week
+//   of that period, and how late those done were
+// It follows each activity through its delays and reschedules, and each
+//   repeating activity back through the occurrences before it
+// And returns the counts, the most delayed activities and the streak of
+//   each repeating activity that is still to be done
+//
+// Alternative flows :-
+//  if the unit is not days, weeks or months then return a message to the user
+//  if the storage cannot be read then return its error
+//  an activity removed before DONE and CANCEL were told apart is counted
+//    as done if it was not delayed or rescheduled
+//
+func CompletionStats(count int, unit string, historian CommandHistorian, clock entities.Clock) (Stats, error) {
+	now := clock.Now().In(entities.Zone)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, entities.Zone)
+	if unit != "day" && unit != "days" && unit != "week" && unit != "weeks" && unit != "month" && unit != "months" {
+		return Stats{}, fmt.Errorf("Expected days, weeks or months but got '%s'", unit)
+	}
+	before, err := delayTimestamp(today, -count, unit)
+	if err != nil {
+		return Stats{}, err
+	}
+	stats := Stats{From: before.AddDate(0, 0, 1), Until: now}
+	for day := stats.From; day.Before(now); day = day.AddDate(0, 0, 1) {
+		stats.Days = append(stats.Days, Tally{Start: day})
+	}
+	for week := startOfWeek(stats.From); week.Before(now); week = week.AddDate(0, 0, 7) {
+		stats.Weeks = append(stats.Weeks, Tally{Start: week})
+	}
+
+	history, err := historian.History()
+	if err != nil {
+		return Stats{}, err
+	}
+	live, err := historian.GetAll()
+	if err != nil {
+		return Stats{}, err
+	}
+	shortenIds(live)
+
+	links := map[string]link{}
+	chains := map[string]*DelayChain{}
+	inPeriod := map[*DelayChain]bool{}
+	outcomes := map[string]string{}
+	var lateness time.Duration
+	for _, operation := range history {
+		removed, added := entities.Activities{}, entities.Activities{}
+		for _, change := range operation.Changes {
+			switch change.Command {
+			case entities.AddCommand:
+				added = append(added, change.Activity)
+				continue
+			case entities.EditCommand:
+				continue
+			}
+			removed = append(removed, change.Activity)
+			if chain, ok := chains[change.Activity.Id]; ok {
+				chain.Activity = change.Activity
+			}
+			outcome := outcomeOf(change.Command, operation.Name)
+			if outcome == "" {
+				continue
+			}
+			outcomes[change.Activity.Id] = outcome
+			if operation.Now.Before(stats.From) {
+				continue
+			}
+			stats.count(operation.Now, outcome)
+			if outcome == "done" {
+				late := operation.Now.Sub(change.Activity.Timestamp)
+				lateness += late
+				if late > 0 {
+					stats.Late++
+				}
+			}
+		}
+
+		if len(removed) != 1 || len(added) != 1 {
+			continue
+		}
+		from, to := removed[0], added[0]
+		switch operation.Name {
+		case "repeat":
+			links[to.Id] = link{from: from, at: operation.Now, repeated: true}
+		case "delay", "reschedule":
+			links[to.Id] = link{from: from, at: operation.Now}
+			chain, ok := chains[from.Id]
+			if !ok {
+				chain = &DelayChain{}
+			}
+			delete(chains, from.Id)
+			chain.Activity = to
+			chain.Delays++
+			chain.Slipped += to.Timestamp.Sub(from.Timestamp)
+			chains[to.Id] = chain
+			if !operation.Now.Before(stats.From) {
+				inPeriod[chain] = true
+			}
+		}
+	}
+	if stats.Done > 0 {
+		stats.AverageLateness = (lateness / time.Duration(stats.Done)).Round(time.Minute)
+	}
+
+	liveById := map[string]entities.OneActivity{}
+	for _, activity := range live {
+		liveById[activity.Id] = activity
+	}
+	for id, chain := range chains {
+		if !inPeriod[chain] {
+			continue
+		}
+		chain.Outcome = outcomes[id]
+		if activity, ok := liveById[id]; ok {
+			chain.Activity = activity
+		}
+		stats.MostDelayed = append(stats.MostDelayed, *chain)
+	}
+	sort.SliceStable(stats.MostDelayed, func(i, j int) bool {
+		a, b := stats.MostDelayed[i], stats.MostDelayed[j]
+		if a.Delays != b.Delays {
+			return a.Delays > b.Delays
+		}
+		if a.Slipped != b.Slipped {
+			return a.Slipped > b.Slipped
+		}
+		return a.Activity.Timestamp.Before(b.Activity.Timestamp)
+	})
+	if len(stats.MostDelayed) > mostDelayedShown {
+		stats.MostDelayed = stats.MostDelayed[0:mostDelayedShown]
+	}
+
+	live.Sort()
+	for _, activity := range live {
+		if activity.HasRepeatCommand() {
+			if rule, err := entities.ParseRepeatRule(activity.CommandTag); err == nil {
+				stats.Streaks = append(stats.Streaks, Streak{activity, streak(activity, rule, links, now)})
+			}
+		}
+	}
+	sort.SliceStable(stats.Streaks, func(i, j int) bool {
+		return stats.Streaks[i].Length > stats.Streaks[j].Length
+	})
+	return stats, nil
+}
+
+// outcomeOf is "done" or "cancelled" for a change that took an activity
+// out of the stream for that reason, or "" if it was only moved
+func outcomeOf(command string, operation string) string {
+	switch command {
+	case entities.DoneCommand:
+		return "done"
+	case entities.CancelCommand:
+		return "cancelled"
+	case entities.DeleteCommand:
+		if operation == "done" || operation == "repeat" {
+			return "done"
+		}
+	}
+	return ""
+}
+
+func (this *Stats) count(at time.Time, outcome string) {
+	for _, tallies := range [][]Tally{this.Days, this.Weeks} {
+		for i := len(tallies) - 1; i >= 0; i-- {
+			if !at.Before(tallies[i].Start) {
+				if outcome == "done" {
+					tallies[i].Done++
+				} else {
+					tallies[i].Cancelled++
+				}
+				break
+			}
+		}
+	}
+	if outcome == "done" {
+		this.Done++
+	} else {
+		this.Cancelled++
+	}
+}
+
+func startOfWeek(day time.Time) time.Time {
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// streak counts back through the occurrences before the activity, through
+// any delays, how many were done in a row before the next would have been
+// due. It is none if the activity itself is already past that.
+func streak(activity entities.OneActivity, rule entities.RepeatRule, links map[string]link, now time.Time) int {
+	if now.After(rule.Next(activity.Timestamp, activity.Timestamp)) {
+		return 0
+	}
+	length := 0
+	for previous, ok := links[activity.Id]; ok; previous, ok = links[previous.from.Id] {
+		if !previous.repeated {
+			continue
+		}
+		due := previous.from.Timestamp
+		if previous.at.After(rule.Next(due, due)) {
+			break
+		}
+		length++
+	}
+	return length
+}
+
+// GetStats is the Stats as lines of text: the counts for each day and
+// week, how late things were done, the most delayed activities and the
+// streaks of the repeating ones.
+func GetStats(count int, unit string, historian CommandHistorian, clock entities.Clock) ([]string, error) {
+	stats, err := CompletionStats(count, unit, historian, clock)
+	if err != nil {
+		return nil, err
+	}
+	output := []string{fmt.Sprintf("Done %d and cancelled %d from %s to %s",
+		stats.Done, stats.Cancelled, stats.From.Format("Monday 2 January"), stats.Until.Format("Monday 2 January"))}
+	for _, day := range stats.Days {
+		output = append(output, fmt.Sprintf("  %-20s %s", day.Start.Format("Mon 2 Jan"), tallyString(day)))
+	}
+	if len(stats.Weeks) > 1 {
+		for _, week := range stats.Weeks {
+			output = append(output, fmt.Sprintf("  %-20s %s", week.Start.Format("Week of Mon 2 Jan"), tallyString(week)))
+		}
+	}
+	if stats.Done > 0 {
+		output = append(output, fmt.Sprintf("%d of %d done late; on average %s", stats.Late, stats.Done, latenessString(stats.AverageLateness)))
+	}
+
+	if len(stats.MostDelayed) > 0 {
+		output = append(output, "Most delayed:")
+	}
+	for _, chain := range stats.MostDelayed {
+		times := "times"
+		if chain.Delays == 1 {
+			times = "time"
+		}
+		outcome := ""
+		if chain.Outcome != "" {
+			outcome = ", then " + chain.Outcome
+		}
+		output = append(output, fmt.Sprintf("  %s (%d %s, %s later%s)",
+			statsLine(chain.Activity), chain.Delays, times, durationString(chain.Slipped), outcome))
+	}
+
+	if len(stats.Streaks) > 0 {
+		output = append(output, "Streaks:")
+	}
+	for _, streak := range stats.Streaks {
+		output = append(output, fmt.Sprintf("  %s (%d in a row)", statsLine(streak.Activity), streak.Length))
+	}
+	return output, nil
+}
+
+func tallyString(tally Tally) string {
+	if tally.Cancelled == 0 {
+		return fmt.Sprintf("%3d done", tally.Done)
+	}
+	return fmt.Sprintf("%3d done, %d cancelled", tally.Done, tally.Cancelled)
+}
+
+// statsLine is the activity as get shows it, or with its whole time and
+// no index if it has been done or cancelled
+func statsLine(activity entities.OneActivity) string {
+	if activity.ShortId != "" {
+		return activity.IndexedString()
+	}
+	return activity.TimeString() + " " + activity.PriorityString() + activity.Body
+}
+
+func latenessString(lateness time.Duration) string {
+	if lateness < 0 {
+		return durationString(-lateness) + " early"
+	}
+	return durationString(lateness) + " late"
+}
+
+// durationString is the duration in days, hours and minutes, leaving out
+// any that are nothing, such as "2 days 3 hours"
+func durationString(duration time.Duration) string {
+	minutes := int(duration.Round(time.Minute) / time.Minute)
+	if minutes < 0 {
+		minutes = -minutes
+	}
+	parts := []string{}
+	for _, unit := range []struct {
+		name    string
+		minutes int
+	}{{"day", 24 * 60}, {"hour", 60}, {"minute", 1}} {
+		if n := minutes / unit.minutes; n > 0 {
+			if n == 1 {
+				parts = append(parts, fmt.Sprintf("1 %s", unit.name))
+			} else {
+				parts = append(parts, fmt.Sprintf("%d %ss", n, unit.name))
+			}
+			minutes -= n * unit.minutes
+		}
+	}
+	if len(parts) == 0 {
+		return "no time"
+	}
+	return strings.Join(parts, " ")
+}
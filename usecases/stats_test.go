@@ -0,0 +1,128 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+// operation makes an operation done at "YYYY-MM-DD HH:MM" UTC
+func operation(name, stamp string, changes ...entities.Change) entities.Operation {
+	return entities.Operation{Name: name, Now: activity("", stamp, "").Timestamp, Changes: changes}
+}
+
+func change(command string, activity entities.OneActivity) entities.Change {
+	return entities.Change{Command: command, Activity: activity}
+}
+
+func statsStore() *fakeStore {
+	car := activity("aaa111", "2014-01-05 09:00", "Wash the car")
+	delayed := activity("bbb222", "2014-01-06 09:00", "Wash the car")
+	rescheduled := activity("ccc333", "2014-01-08 09:00", "Wash the car")
+	cat := activity("eee555", "2014-01-09 09:00", "Feed the cat")
+	duolingo := []entities.OneActivity{}
+	for i, stamp := range []string{"2014-01-07 12:00", "2014-01-08 12:00", "2014-01-09 12:00", "2014-01-10 12:00"} {
+		occurrence := activity(strings.Repeat(string(rune('p'+i)), 6), stamp, "Duolingo")
+		occurrence.CommandTag = "every-n-days:1"
+		duolingo = append(duolingo, occurrence)
+	}
+
+	store := newFakeStore(duolingo[3])
+	store.operations = []entities.Operation{
+		operation("done", "2014-01-03 12:00", change(entities.DeleteCommand, activity("fff666", "2014-01-01 12:00", "Before"))),
+		operation("new", "2014-01-04 12:00", change(entities.AddCommand, car)),
+		operation("delay", "2014-01-05 10:00", change(entities.DeleteCommand, car), change(entities.AddCommand, delayed)),
+		operation("reschedule", "2014-01-06 10:00", change(entities.DeleteCommand, delayed), change(entities.AddCommand, rescheduled)),
+		operation("new", "2014-01-06 11:00", change(entities.AddCommand, duolingo[0])),
+		operation("repeat", "2014-01-07 13:00", change(entities.DoneCommand, duolingo[0]), change(entities.AddCommand, duolingo[1])),
+		operation("done", "2014-01-08 10:00", change(entities.DoneCommand, rescheduled)),
+		operation("repeat", "2014-01-08 20:00", change(entities.DoneCommand, duolingo[1]), change(entities.AddCommand, duolingo[2])),
+		operation("cancel", "2014-01-09 09:00", change(entities.CancelCommand, cat)),
+		operation("repeat", "2014-01-09 12:30", change(entities.DoneCommand, duolingo[2]), change(entities.AddCommand, duolingo[3])),
+	}
+	return store
+}
+
+func TestCompletionStats(t *testing.T) {
+	entities.Zone = time.UTC
+	stats, err := CompletionStats(1, "week", statsStore(), clockAt("2014-01-10 12:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.From.Format("2006-01-02 15:04") != "2014-01-04 00:00" || len(stats.Days) != 7 {
+		t.Errorf("got %d days from %s, want 7 from 2014-01-04", len(stats.Days), stats.From)
+	}
+	if stats.Done != 4 || stats.Cancelled != 1 || stats.Late != 4 {
+		t.Errorf("got %d done, %d cancelled and %d late, want 4, 1 and 4", stats.Done, stats.Cancelled, stats.Late)
+	}
+	days := []string{}
+	for _, day := range stats.Days {
+		days = append(days, tallyString(day))
+	}
+	if got := strings.Join(days, "|"); got != "  0 done|  0 done|  0 done|  1 done|  2 done|  1 done, 1 cancelled|  0 done" {
+		t.Errorf("got the days %q", got)
+	}
+	if len(stats.Weeks) != 2 || stats.Weeks[1].Start.Format("2006-01-02") != "2014-01-06" || stats.Weeks[1].Done != 4 {
+		t.Errorf("got the weeks %+v, want the week of 2014-01-06 with 4 done", stats.Weeks)
+	}
+	if stats.AverageLateness != 2*time.Hour+38*time.Minute {
+		t.Errorf("got an average lateness of %s", stats.AverageLateness)
+	}
+	if len(stats.MostDelayed) != 1 {
+		t.Fatalf("got the most delayed %+v, want the car", stats.MostDelayed)
+	}
+	if chain := stats.MostDelayed[0]; chain.Activity.Id != "ccc333" || chain.Delays != 2 || chain.Slipped != 72*time.Hour || chain.Outcome != "done" {
+		t.Errorf("got %+v, want the car delayed twice by 3 days in all, then done", chain)
+	}
+	if len(stats.Streaks) != 1 || stats.Streaks[0].Activity.Id != "ssssss" || stats.Streaks[0].Length != 3 {
+		t.Errorf("got the streaks %+v, want Duolingo 3 in a row", stats.Streaks)
+	}
+}
+
+func TestStreakBreaks(t *testing.T) {
+	entities.Zone = time.UTC
+	store := statsStore()
+	// the second occurrence was done a day and more after it was due
+	store.operations[7].Now = activity("", "2014-01-09 12:30", "").Timestamp
+	stats, err := CompletionStats(1, "week", store, clockAt("2014-01-10 12:00"))
+	if err != nil || len(stats.Streaks) != 1 || stats.Streaks[0].Length != 1 {
+		t.Errorf("got %+v, %v, want a streak of 1 since the late one", stats.Streaks, err)
+	}
+	// and now the one that is due has been missed
+	stats, err = CompletionStats(1, "week", store, clockAt("2014-01-11 12:01"))
+	if err != nil || len(stats.Streaks) != 1 || stats.Streaks[0].Length != 0 {
+		t.Errorf("got %+v, %v, want no streak", stats.Streaks, err)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	entities.Zone = time.UTC
+	got, err := GetStats(1, "week", statsStore(), clockAt("2014-01-10 12:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := strings.Join(got, "\n")
+	for _, want := range []string{
+		"Done 4 and cancelled 1 from Saturday 4 January to Friday 10 January",
+		"  Thu 9 Jan              1 done, 1 cancelled",
+		"  Week of Mon 6 Jan      4 done, 1 cancelled",
+		"4 of 4 done late; on average 2 hours 38 minutes late",
+		"Most delayed:\n  2014-01-08 09:00 Wash the car (2 times, 3 days later, then done)",
+		"Streaks:\n  [\033[1msss\033[0m]* 2014-01-10 12:00 Duolingo (3 in a row)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("got\n%s\nwant it to contain %q", output, want)
+		}
+	}
+	if _, err := GetStats(3, "hours", statsStore(), clockAt("2014-01-10 12:00")); err == nil {
+		t.Errorf("got no error for a period in hours")
+	}
+}
@@ -0,0 +1,22 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+// Store is everything the use cases ask of storage, for a program that
+// hands the same storage to all of them. Each use case still asks only for
+// the narrow interface it needs.
+type Store interface {
+	CommandDelayer
+	CommandCompleter
+	CommandGrepper
+	CommandCompacter
+	CommandUndoer
+	CommandMigrator
+	CommandEditor
+	CommandCanceller
+	CommandHistorian
+}
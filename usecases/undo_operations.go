@@ -0,0 +1,69 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"fmt"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+type CommandUndoer interface {
+	CommandGetter
+	Undo(n int) ([]entities.Operation, error)
+}
+
+//
+// Basic flow :-
+// The user passes how many operations to undo.
+// The usecase asks the undoer to revert that many of the most recent
+// operations that have not already been undone
+// And returns a description of each operation that was undone: a line
+// naming it followed by a line for each activity that was put back ('+'),
+// taken away ('-') or changed back to how it was ('~')
+//
+// Alternative flows :-
+//  if there is nothing left to undo then return a message to the user
+//  if fewer operations are left than were asked for then undo those that are
+//  if the storage cannot be read or written then return its error along
+//    with the descriptions of any operations already undone
+//
+func UndoOperations(n int, undoer CommandUndoer) ([]string, error) {
+	undone, err := undoer.Undo(n)
+	if len(undone) == 0 && err == nil {
+		return nil, fmt.Errorf("Nothing to undo")
+	}
+
+	affected := entities.Activities{}
+	for _, operation := range undone {
+		for _, change := range operation.Changes {
+			affected = append(affected, change.Activity)
+		}
+	}
+	length := minIdLength
+	if live, err := undoer.GetAll(); err == nil {
+		length = uniqueIdLength(append(live, affected...))
+	}
+
+	output := []string{}
+	for _, operation := range undone {
+		output = append(output, fmt.Sprintf("Undid %s:", operation.Name))
+		for _, change := range operation.Inverse() {
+			sign := "+"
+			switch change.Command {
+			case entities.DeleteCommand:
+				sign = "-"
+			case entities.EditCommand:
+				sign = "~"
+			}
+			activity := change.Activity
+			activity.ShortId = prefix(activity.Id, length)
+			output = append(output, fmt.Sprintf("  %s %s", sign, activity.IndexedString()))
+		}
+	}
+	return output, err
+}
@@ -0,0 +1,49 @@
+/*
+Acts - add, display and delete activities to do.
+Copyright (C) 2026  Patrick Borgeest
+See LICENSE.txt for terms of usage.
+*/
+
+package usecases
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Fepelus/ActivityStream/entities"
+)
+
+func TestUndoOperations(t *testing.T) {
+	entities.Zone = time.UTC
+	old := activity("aaa111", "2014-01-01 12:00", "Wash the car")
+	delayed := activity("bbb222", "2014-01-02 12:00", "Wash the car")
+	store := newFakeStore(delayed)
+	store.operations = []entities.Operation{
+		{Name: "new", Changes: []entities.Change{{Command: entities.AddCommand, Activity: old}}},
+		{Name: "delay", Changes: []entities.Change{
+			{Command: entities.DeleteCommand, Activity: old},
+			{Command: entities.AddCommand, Activity: delayed},
+		}},
+	}
+
+	got, err := UndoOperations(1, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Undid delay:",
+		"  - [\033[1mbbb\033[0m] 2014-01-02 12:00 Wash the car",
+		"  + [\033[1maaa\033[0m] 2014-01-01 12:00 Wash the car",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := UndoOperations(5, store); err != nil {
+		t.Errorf("undoing more than there are: %s", err)
+	}
+	if _, err := UndoOperations(1, store); err == nil || err.Error() != "Nothing to undo" {
+		t.Errorf("got %v, want 'Nothing to undo'", err)
+	}
+}